@@ -0,0 +1,52 @@
+// Operaciones atómicas sobre float64 para el entrenamiento asíncrono sin bloqueos (Hogwild!): varios
+// workers leen y actualizan los mismos parámetros sin mutex. Cada lectura y cada suma es atómica, así que
+// no hay carreras de datos, pero un worker puede calcular su actualización con valores que otro ya ha
+// cambiado; el algoritmo acepta esa inconsistencia a cambio de no serializar las actualizaciones
+package atomicfloat
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Puntero a los bits del float64 (mismo tamaño y alineación que un uint64)
+func bits(addr *float64) *uint64 {
+	return (*uint64)(unsafe.Pointer(addr))
+}
+
+// Leer atómicamente el valor
+func Load(addr *float64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(bits(addr)))
+}
+
+// Escribir atómicamente el valor
+func Store(addr *float64, value float64) {
+	atomic.StoreUint64(bits(addr), math.Float64bits(value))
+}
+
+// Sumar delta al valor atómicamente (compare-and-swap hasta que ninguna otra escritura se interponga)
+func Add(addr *float64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits(addr))
+		if atomic.CompareAndSwapUint64(bits(addr), old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Copiar atómicamente los valores de src en dst (una instantánea elemento a elemento, no del vector entero)
+func LoadSlice(dst, src []float64) {
+	for i := range src {
+		dst[i] = Load(&src[i])
+	}
+}
+
+// Sumar atómicamente deltas[i] a values[i] para cada i
+func AddSlice(values, deltas []float64) {
+	for i, delta := range deltas {
+		if delta != 0 {
+			Add(&values[i], delta)
+		}
+	}
+}
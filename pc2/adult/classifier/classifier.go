@@ -0,0 +1,72 @@
+package classifier
+
+import (
+	"adult/preprocess"
+	"errors"
+	"sync"
+)
+
+// Error devuelto por Fit cuando no hay registros para entrenar
+var ErrNoRecords = errors.New("no hay registros de entrenamiento")
+
+// Interfaz común de los clasificadores del dataset adult (ANN, SVM, Random Forest)
+type Classifier interface {
+	// Entrenar el modelo con los registros de entrenamiento
+	Fit(records []preprocess.Record) error
+	// Etiqueta predicha (preprocess.PositiveLabel o preprocess.NegativeLabel)
+	Predict(record preprocess.Record) string
+	// Probabilidad estimada de la clase positiva (>50K), en [0, 1]
+	PredictProba(record preprocess.Record) float64
+	// Etiquetas predichas para varios registros, en el mismo orden
+	PredictBatch(records []preprocess.Record) []string
+}
+
+// Convertir una probabilidad de la clase positiva en etiqueta
+func LabelFromProba(proba float64) string {
+	if proba > 0.5 {
+		return preprocess.PositiveLabel
+	}
+	return preprocess.NegativeLabel
+}
+
+// Predecir varios registros repartiéndolos en bloques contiguos entre workers
+// (con workers <= 1 se predice secuencialmente)
+func BatchPredict(predict func(preprocess.Record) string, records []preprocess.Record, workers int) []string {
+	predictions := make([]string, len(records))
+	if workers <= 1 {
+		for i, record := range records {
+			predictions[i] = predict(record)
+		}
+		return predictions
+	}
+
+	chunkSize := (len(records) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(records); start += chunkSize {
+		end := min(start+chunkSize, len(records))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				predictions[i] = predict(records[i]) // Cada worker escribe solo en su bloque
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	return predictions
+}
+
+// Precisión del clasificador sobre los registros
+func Accuracy(c Classifier, records []preprocess.Record) float64 {
+	if len(records) == 0 {
+		return 0
+	}
+	correct := 0
+	for i, prediction := range c.PredictBatch(records) {
+		if prediction == records[i].Income {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(records))
+}
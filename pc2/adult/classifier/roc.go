@@ -0,0 +1,71 @@
+package classifier
+
+import (
+	"adult/preprocess"
+	"math"
+	"sort"
+)
+
+// Punto de la curva ROC: tasas obtenidas al predecir la clase positiva cuando la probabilidad >= Threshold
+type ROCPoint struct {
+	Threshold         float64
+	FalsePositiveRate float64
+	TruePositiveRate  float64
+}
+
+// Curva ROC de las probabilidades del clasificador sobre los registros, con un punto por cada
+// probabilidad distinta (de mayor a menor umbral) más el origen
+func ROC(c Classifier, records []preprocess.Record) []ROCPoint {
+	type scored struct {
+		proba    float64
+		positive bool
+	}
+	scores := make([]scored, len(records))
+	positives, negatives := 0, 0
+	for i, record := range records {
+		positive := record.Income == preprocess.PositiveLabel
+		scores[i] = scored{proba: c.PredictProba(record), positive: positive}
+		if positive {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	sort.SliceStable(scores, func(a, b int) bool { return scores[a].proba > scores[b].proba })
+
+	rate := func(count, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(count) / float64(total)
+	}
+	points := []ROCPoint{{Threshold: math.Inf(1)}} // Con umbral infinito no se predice ningún positivo
+	truePositives, falsePositives := 0, 0
+	for i, s := range scores {
+		if s.positive {
+			truePositives++
+		} else {
+			falsePositives++
+		}
+		// Los registros con la misma probabilidad cambian de clase a la vez
+		if i+1 < len(scores) && scores[i+1].proba == s.proba {
+			continue
+		}
+		points = append(points, ROCPoint{
+			Threshold:         s.proba,
+			FalsePositiveRate: rate(falsePositives, negatives),
+			TruePositiveRate:  rate(truePositives, positives),
+		})
+	}
+	return points
+}
+
+// Área bajo la curva ROC (regla del trapecio)
+func AUC(points []ROCPoint) float64 {
+	area := 0.0
+	for i := 1; i < len(points); i++ {
+		width := points[i].FalsePositiveRate - points[i-1].FalsePositiveRate
+		area += width * (points[i].TruePositiveRate + points[i-1].TruePositiveRate) / 2
+	}
+	return area
+}
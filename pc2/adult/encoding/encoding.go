@@ -0,0 +1,189 @@
+package encoding
+
+import (
+	"adult/preprocess"
+	"sort"
+	"sync"
+)
+
+// Método para convertir las columnas categóricas en números
+type Method int
+
+const (
+	OneHot  Method = iota // Una columna binaria por categoría
+	Ordinal               // Índice de la categoría
+	Target                // Media (suavizada) de la etiqueta para la categoría
+)
+
+// Peso de la media global al suavizar la codificación por objetivo
+const targetSmoothing = 20.0
+
+// Codificador de características ajustado con los datos de entrenamiento
+type Encoder struct {
+	Method      Method
+	Categories  [][]string  // Categorías vistas por cada columna de preprocess.CategoricalFeatures (incluye Unknown)
+	TargetMeans [][]float64 // Media de la etiqueta por categoría (solo para Target)
+	index       []map[string]int
+	indexOnce   sync.Once // El índice se construye al primer uso (también tras cargar un modelo guardado)
+}
+
+// Ajustar el codificador con los registros de entrenamiento
+func Fit(records []preprocess.Record, method Method) *Encoder {
+	numColumns := len(preprocess.CategoricalFeatures)
+	counts := make([]map[string]int, numColumns)
+	positives := make([]map[string]int, numColumns)
+	for i := range counts {
+		counts[i] = map[string]int{preprocess.Unknown: 0}
+		positives[i] = make(map[string]int)
+	}
+
+	totalPositives := 0
+	for _, record := range records {
+		positive := record.Income == preprocess.PositiveLabel
+		if positive {
+			totalPositives++
+		}
+		for i, feature := range preprocess.CategoricalFeatures {
+			value := record.Category(feature)
+			counts[i][value]++
+			if positive {
+				positives[i][value]++
+			}
+		}
+	}
+
+	encoder := &Encoder{
+		Method:     method,
+		Categories: make([][]string, numColumns),
+	}
+	for i := range counts {
+		for value := range counts[i] {
+			encoder.Categories[i] = append(encoder.Categories[i], value)
+		}
+		sort.Strings(encoder.Categories[i]) // Orden fijo para que la codificación sea reproducible
+	}
+
+	if method == Target {
+		prior := 0.0
+		if len(records) > 0 {
+			prior = float64(totalPositives) / float64(len(records))
+		}
+		encoder.TargetMeans = make([][]float64, numColumns)
+		for i, categories := range encoder.Categories {
+			encoder.TargetMeans[i] = make([]float64, len(categories))
+			for j, value := range categories {
+				count := float64(counts[i][value])
+				encoder.TargetMeans[i][j] = (float64(positives[i][value]) + targetSmoothing*prior) / (count + targetSmoothing)
+			}
+		}
+	}
+
+	return encoder
+}
+
+// Construir los índices categoría -> posición
+func (e *Encoder) buildIndex() {
+	e.index = make([]map[string]int, len(e.Categories))
+	for i, categories := range e.Categories {
+		e.index[i] = make(map[string]int, len(categories))
+		for j, value := range categories {
+			e.index[i][value] = j
+		}
+	}
+}
+
+// Posición de la categoría; las categorías no vistas en el entrenamiento van a Unknown
+func (e *Encoder) categoryIndex(column int, value string) int {
+	e.indexOnce.Do(e.buildIndex)
+	if j, ok := e.index[column][value]; ok {
+		return j
+	}
+	return e.index[column][preprocess.Unknown]
+}
+
+// Posición de la columna dentro de preprocess.CategoricalFeatures (-1 si no es categórica)
+func categoricalColumn(feature preprocess.Feature) int {
+	for i, f := range preprocess.CategoricalFeatures {
+		if f == feature {
+			return i
+		}
+	}
+	return -1
+}
+
+// Código (índice) de la categoría de una columna categórica; las no vistas usan el código de Unknown
+func (e *Encoder) Code(feature preprocess.Feature, value string) int {
+	return e.categoryIndex(categoricalColumn(feature), value)
+}
+
+// Categorías conocidas de una columna categórica, ordenadas por código
+func (e *Encoder) CategoriesOf(feature preprocess.Feature) []string {
+	return e.Categories[categoricalColumn(feature)]
+}
+
+// Número de características que produce Transform
+func (e *Encoder) NumFeatures() int {
+	n := len(preprocess.NumericFeatures)
+	for _, categories := range e.Categories {
+		if e.Method == OneHot {
+			n += len(categories)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// Nombres de las características en el mismo orden que Transform
+func (e *Encoder) FeatureNames() []string {
+	names := make([]string, 0, e.NumFeatures())
+	for _, feature := range preprocess.NumericFeatures {
+		names = append(names, feature.String())
+	}
+	for i, feature := range preprocess.CategoricalFeatures {
+		if e.Method != OneHot {
+			names = append(names, feature.String())
+			continue
+		}
+		for _, value := range e.Categories[i] {
+			names = append(names, feature.String()+"="+value)
+		}
+	}
+	return names
+}
+
+// Convertir un registro en su vector de características
+func (e *Encoder) Transform(record preprocess.Record) []float64 {
+	features := make([]float64, 0, e.NumFeatures())
+	for _, feature := range preprocess.NumericFeatures {
+		features = append(features, record.Numeric(feature))
+	}
+
+	for i, feature := range preprocess.CategoricalFeatures {
+		j := e.categoryIndex(i, record.Category(feature))
+		switch e.Method {
+		case OneHot:
+			for k := range e.Categories[i] {
+				if k == j {
+					features = append(features, 1)
+				} else {
+					features = append(features, 0)
+				}
+			}
+		case Ordinal:
+			features = append(features, float64(j))
+		case Target:
+			features = append(features, e.TargetMeans[i][j])
+		}
+	}
+	return features
+}
+
+// Convertir todos los registros
+func (e *Encoder) TransformAll(records []preprocess.Record) [][]float64 {
+	features := make([][]float64, len(records))
+	for i, record := range records {
+		features[i] = e.Transform(record)
+	}
+	return features
+}
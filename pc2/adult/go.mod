@@ -0,0 +1,3 @@
+module adult
+
+go 1.23.0
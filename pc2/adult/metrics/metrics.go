@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Métricas de una época de entrenamiento
+type Epoch struct {
+	Epoch              int           // Número de época (desde 1)
+	TrainLoss          float64       // Pérdida de entrenamiento acumulada durante la época (con los pesos previos a cada actualización)
+	ValidationLoss     float64       // Pérdida sobre el conjunto de validación al final de la época (NaN si no hay)
+	ValidationAccuracy float64       // Precisión sobre el conjunto de validación (NaN si no hay o el modelo no clasifica)
+	Elapsed            time.Duration // Tiempo de entrenamiento desde el inicio hasta el final de la época
+}
+
+// Observador del entrenamiento: recibe las métricas al final de cada época
+type Observer interface {
+	ObserveEpoch(epoch Epoch)
+}
+
+// Adaptador para usar una función como observador
+type ObserverFunc func(epoch Epoch)
+
+// Llamar a la función con las métricas de la época
+func (f ObserverFunc) ObserveEpoch(epoch Epoch) {
+	f(epoch)
+}
+
+// Notificar las métricas de una época a todos los observadores (los nil se ignoran)
+func Notify(observers []Observer, epoch Epoch) {
+	for _, observer := range observers {
+		if observer != nil {
+			observer.ObserveEpoch(epoch)
+		}
+	}
+}
+
+// Función para calcular las métricas de la época (las de validación con evaluate, NaN si no hay registros) y notificarlas
+func Report[R any](observers []Observer, epoch int, trainLoss float64, start time.Time, validation []R, evaluate func([]R) (loss, accuracy float64)) Epoch {
+	m := Epoch{Epoch: epoch, TrainLoss: trainLoss, ValidationLoss: math.NaN(), ValidationAccuracy: math.NaN(), Elapsed: time.Since(start)}
+	if len(validation) > 0 {
+		m.ValidationLoss, m.ValidationAccuracy = evaluate(validation)
+	}
+	Notify(observers, m)
+	return m
+}
+
+// Parada temprana: sigue la menor pérdida de validación vista y decide cuándo dejar de entrenar
+type EarlyStopping struct {
+	Patience  int     // Épocas seguidas sin mejorar antes de parar (<= 0 para no parar antes de tiempo)
+	BestLoss  float64 // Menor pérdida de validación registrada
+	BestEpoch int     // Época (desde 1) con la menor pérdida, 0 si aún no hay ninguna
+	restore   func()  // Vuelve a los parámetros guardados en la mejor época (nil si no se guardó ninguna)
+}
+
+// Crear el seguimiento de la parada temprana con la paciencia dada
+func NewEarlyStopping(patience int) *EarlyStopping {
+	return &EarlyStopping{Patience: patience, BestLoss: math.Inf(1)}
+}
+
+// Registrar la pérdida de validación de una época: improved indica que es la mejor hasta ahora (hay que
+// guardar los pesos) y stop que se ha agotado la paciencia. Una pérdida NaN (entrenamiento divergente)
+// nunca mejora
+func (es *EarlyStopping) Update(epoch int, loss float64) (improved, stop bool) {
+	if loss < es.BestLoss {
+		es.BestLoss, es.BestEpoch = loss, epoch
+		return true, false
+	}
+	return false, es.Patience > 0 && epoch-es.BestEpoch >= es.Patience
+}
+
+// Función para registrar una época con validación: si es la mejor, snapshot copia los parámetros del modelo y
+// devuelve la función que los restaura. Indica si hay que dejar de entrenar
+func (es *EarlyStopping) Check(epoch Epoch, snapshot func() (restore func())) bool {
+	improved, stop := es.Update(epoch.Epoch, epoch.ValidationLoss)
+	if improved {
+		es.restore = snapshot()
+	}
+	return stop
+}
+
+// Función para volver a los parámetros de la mejor época (si se guardó alguna) y devolver su número
+func (es *EarlyStopping) Restore() int {
+	if es.restore != nil {
+		es.restore()
+	}
+	return es.BestEpoch
+}
+
+// Pérdida logística de una probabilidad estimada de la clase positiva respecto a la etiqueta (0 o 1),
+// recortando la probabilidad para evitar log(0)
+func LogLoss(proba, label float64) float64 {
+	const eps = 1e-15
+	proba = math.Min(math.Max(proba, eps), 1-eps)
+	return -(label*math.Log(proba) + (1-label)*math.Log(1-proba))
+}
+
+// Escritor de métricas en CSV: una fila por época con el nombre de la ejecución, para poder comparar
+// varias ejecuciones (por ejemplo la secuencial y la concurrente) en el mismo archivo
+type CSVWriter struct {
+	mu     sync.Mutex // Las ejecuciones pueden notificar desde goroutines distintas
+	writer *csv.Writer
+	file   *os.File // Archivo creado por Create (nil si se escribe en otro destino)
+	err    error    // Primer error de escritura
+}
+
+// Crear un escritor de métricas y escribir la cabecera
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	c := &CSVWriter{writer: csv.NewWriter(w)}
+	c.write([]string{"run", "epoch", "train_loss", "validation_loss", "validation_accuracy", "elapsed_seconds"})
+	return c
+}
+
+// Crear el archivo CSV de métricas en la ruta dada (Close vuelca las filas y lo cierra)
+func Create(path string) (*CSVWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el archivo de métricas: %v", err)
+	}
+	c := NewCSVWriter(file)
+	c.file = file
+	return c, nil
+}
+
+// Observador que escribe las épocas de la ejecución con el nombre dado (nil si el escritor es nil, para
+// que quien no pide métricas no tenga que comprobarlo)
+func (c *CSVWriter) Run(name string) Observer {
+	if c == nil {
+		return nil
+	}
+	return ObserverFunc(func(epoch Epoch) {
+		c.write([]string{
+			name,
+			strconv.Itoa(epoch.Epoch),
+			formatFloat(epoch.TrainLoss),
+			formatFloat(epoch.ValidationLoss),
+			formatFloat(epoch.ValidationAccuracy),
+			formatFloat(epoch.Elapsed.Seconds()),
+		})
+	})
+}
+
+// Escribir una fila, guardando el primer error
+func (c *CSVWriter) write(record []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = c.writer.Write(record)
+	}
+}
+
+// Volcar las filas pendientes y devolver el primer error de escritura
+func (c *CSVWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writer.Flush()
+	if c.err == nil {
+		c.err = c.writer.Error()
+	}
+	return c.err
+}
+
+// Volcar las filas pendientes y cerrar el archivo creado por Create
+func (c *CSVWriter) Close() error {
+	err := c.Flush()
+	if c.file != nil {
+		if closeErr := c.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Número en texto para el CSV (vacío si es NaN)
+func formatFloat(x float64) string {
+	if math.IsNaN(x) {
+		return ""
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
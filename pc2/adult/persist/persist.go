@@ -0,0 +1,126 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cabecera común de todos los archivos de modelos
+type header struct {
+	Kind    string `json:"kind"`    // Tipo de modelo ("ann", "svm", "random-forest", ...)
+	Version int    `json:"version"` // Versión del formato propia de cada tipo de modelo
+}
+
+// Archivo JSON: cabecera y modelo en un solo objeto para poder inspeccionarlo
+type jsonFile struct {
+	header
+	Model json.RawMessage `json:"model"`
+}
+
+// Indica si la ruta corresponde al formato JSON (extensión .json); cualquier otra usa el formato binario
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// Guardar un modelo en disco (JSON si la ruta termina en .json, binario gob en otro caso) con la versión
+// del formato de su tipo, que cada paquete incrementa cuando cambia la estructura que guarda
+func Save(path string, kind string, version int, model any) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error al crear el archivo: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	h := header{Kind: kind, Version: version}
+	if isJSON(path) {
+		data, err := json.Marshal(model)
+		if err != nil {
+			return fmt.Errorf("error al codificar el modelo: %v", err)
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(jsonFile{header: h, Model: data}); err != nil {
+			return fmt.Errorf("error al escribir el modelo: %v", err)
+		}
+	} else {
+		encoder := gob.NewEncoder(writer)
+		if err := encoder.Encode(h); err != nil {
+			return fmt.Errorf("error al escribir la cabecera: %v", err)
+		}
+		if err := encoder.Encode(model); err != nil {
+			return fmt.Errorf("error al escribir el modelo: %v", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error al escribir el archivo: %v", err)
+	}
+	return file.Close()
+}
+
+// Guardar un modelo con save en formato JSON (para inspeccionarlo) y binario (compacto) como dir/name.json y
+// dir/name.gob, mostrando las rutas escritas o el primer error
+func SaveAll(save func(path string) error, dir, name string) {
+	for _, ext := range []string{".json", ".gob"} {
+		path := filepath.Join(dir, name+ext)
+		if err := save(path); err != nil {
+			fmt.Printf("Error al guardar el modelo: %v\n", err)
+			return
+		}
+		fmt.Printf("Modelo guardado en %s\n", path)
+	}
+}
+
+// Cargar un modelo guardado con Save, verificando el tipo y la versión del formato
+func Load(path string, kind string, version int, model any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error al abrir el archivo: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if isJSON(path) {
+		var f jsonFile
+		if err := json.NewDecoder(reader).Decode(&f); err != nil {
+			return fmt.Errorf("error al leer el archivo: %v", err)
+		}
+		if err := checkHeader(f.header, kind, version); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(f.Model, model); err != nil {
+			return fmt.Errorf("error al decodificar el modelo: %v", err)
+		}
+		return nil
+	}
+
+	decoder := gob.NewDecoder(reader)
+	var h header
+	if err := decoder.Decode(&h); err != nil {
+		return fmt.Errorf("error al leer la cabecera: %v", err)
+	}
+	if err := checkHeader(h, kind, version); err != nil {
+		return err
+	}
+	if err := decoder.Decode(model); err != nil {
+		return fmt.Errorf("error al decodificar el modelo: %v", err)
+	}
+	return nil
+}
+
+// Verificar que el archivo contiene el tipo de modelo esperado en una versión soportada
+func checkHeader(h header, kind string, version int) error {
+	if h.Kind != kind {
+		return fmt.Errorf("el archivo contiene un modelo %q, se esperaba %q", h.Kind, kind)
+	}
+	if h.Version != version {
+		return fmt.Errorf("versión de formato %d no soportada para %q (se esperaba %d)", h.Version, kind, version)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package preprocess
+
+// Identificador de una columna del dataset, en el mismo orden que en adult.data
+type Feature int
+
+const (
+	Age Feature = iota
+	WorkClass
+	Fnlwgt
+	Education
+	EducationNum
+	MaritalStatus
+	Occupation
+	Relationship
+	Race
+	Sex
+	CapitalGain
+	CapitalLoss
+	HoursPerWeek
+	NativeCountry
+)
+
+// Nombres de las columnas (para mensajes y exportaciones)
+var featureNames = [...]string{
+	"Age", "WorkClass", "Fnlwgt", "Education", "EducationNum", "MaritalStatus", "Occupation",
+	"Relationship", "Race", "Sex", "CapitalGain", "CapitalLoss", "HoursPerWeek", "NativeCountry",
+}
+
+// Todas las columnas usadas como características, en el orden de adult.data
+var AllFeatures = []Feature{
+	Age, WorkClass, Fnlwgt, Education, EducationNum, MaritalStatus, Occupation,
+	Relationship, Race, Sex, CapitalGain, CapitalLoss, HoursPerWeek, NativeCountry,
+}
+
+// Columnas numéricas del dataset
+var NumericFeatures = []Feature{Age, Fnlwgt, EducationNum, CapitalGain, CapitalLoss, HoursPerWeek}
+
+// Columnas categóricas del dataset
+var CategoricalFeatures = []Feature{WorkClass, Education, MaritalStatus, Occupation, Relationship, Race, Sex, NativeCountry}
+
+// Nombre de la columna
+func (f Feature) String() string {
+	return featureNames[f]
+}
+
+// Buscar una columna por su nombre (por ejemplo "Age" o "NativeCountry")
+func ParseFeature(name string) (Feature, bool) {
+	for i, featureName := range featureNames {
+		if featureName == name {
+			return Feature(i), true
+		}
+	}
+	return 0, false
+}
+
+// Indica si la columna es categórica
+func (f Feature) IsCategorical() bool {
+	switch f {
+	case Age, Fnlwgt, EducationNum, CapitalGain, CapitalLoss, HoursPerWeek:
+		return false
+	}
+	return true
+}
+
+// Valor de una columna numérica (0 para columnas categóricas)
+func (r Record) Numeric(f Feature) float64 {
+	switch f {
+	case Age:
+		return float64(r.Age)
+	case Fnlwgt:
+		return float64(r.Fnlwgt)
+	case EducationNum:
+		return float64(r.EducationNum)
+	case CapitalGain:
+		return float64(r.CapitalGain)
+	case CapitalLoss:
+		return float64(r.CapitalLoss)
+	case HoursPerWeek:
+		return float64(r.HoursPerWeek)
+	}
+	return 0
+}
+
+// Valor de una columna categórica ("" para columnas numéricas)
+func (r Record) Category(f Feature) string {
+	switch f {
+	case WorkClass:
+		return r.WorkClass
+	case Education:
+		return r.Education
+	case MaritalStatus:
+		return r.MaritalStatus
+	case Occupation:
+		return r.Occupation
+	case Relationship:
+		return r.Relationship
+	case Race:
+		return r.Race
+	case Sex:
+		return r.Sex
+	case NativeCountry:
+		return r.NativeCountry
+	}
+	return ""
+}
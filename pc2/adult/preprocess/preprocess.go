@@ -1,13 +1,13 @@
 package preprocess
 
 import (
+	"adult/random"
 	"bufio"
 	"fmt"
 	"math/rand"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // Estructura para almacenar una instancia del dataset
@@ -29,8 +29,40 @@ type Record struct {
 	Income        string
 }
 
+// Categoría usada para los valores faltantes
+const Unknown = "Unknown"
+
+// Etiquetas de la clase (ingreso)
+const (
+	PositiveLabel = ">50K"
+	NegativeLabel = "<=50K"
+)
+
 // Cargar los datos del archivo, reemplazar valores faltantes y aumentar el dataset hasta el millón
-func LoadAndPreprocess(filePath string, numRecords int) ([]Record, error) {
+// (la semilla hace que los registros generados sean siempre los mismos)
+func LoadAndPreprocess(filePath string, numRecords int, seed int64) ([]Record, error) {
+	records, err := ReadRecords(filePath, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generar datos adicionales si es necesario
+	if len(records) > 0 && len(records) < numRecords {
+		missing := numRecords - len(records)
+		records = append(records, generateAdditionalRecords(records, missing, random.New(seed))...)
+	}
+
+	return records, nil
+}
+
+// Cargar el conjunto de prueba oficial (adult.test) sin aumentar los datos
+func LoadTestData(filePath string) ([]Record, error) {
+	return ReadRecords(filePath, 0)
+}
+
+// Leer las líneas válidas del archivo y convertirlas en registros, sin aumentar los datos; con limit > 0
+// se detiene tras los primeros limit registros
+func ReadRecords(filePath string, limit int) ([]Record, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error al abrir el archivo: %v", err)
@@ -41,20 +73,18 @@ func LoadAndPreprocess(filePath string, numRecords int) ([]Record, error) {
 	scanner := bufio.NewScanner(file)
 
 	// Leer línea por línea
-	for scanner.Scan() {
+	for (limit <= 0 || len(records) < limit) && scanner.Scan() {
 		line := scanner.Text()
+		// adult.test comienza con la cabecera "|1x3 Cross validator"
+		if strings.HasPrefix(line, "|") {
+			continue
+		}
 		record := parseRecord(line)
 		if record != nil {
 			records = append(records, *record)
 		}
 	}
 
-	// Generar datos adicionales si es necesario
-	if len(records) < numRecords {
-		missing := numRecords - len(records)
-		records = append(records, generateAdditionalRecords(records, missing)...)
-	}
-
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error al leer el archivo: %v", err)
 	}
@@ -96,29 +126,33 @@ func parseRecord(line string) *Record {
 		CapitalLoss:   capitalLoss,
 		HoursPerWeek:  hoursPerWeek,
 		NativeCountry: nativeCountry,
-		Income:        fields[14],
+		Income:        normalizeLabel(fields[14]),
 	}
 }
 
+// En adult.test las etiquetas terminan con punto ("<=50K."), se elimina para unificarlas con adult.data
+func normalizeLabel(label string) string {
+	return strings.TrimSuffix(strings.TrimSpace(label), ".")
+}
+
 // Reemplazar valores faltantes con una categoría común
 func replaceMissing(value string) string {
 	if value == "?" {
-		return "Unknown"
+		return Unknown
 	}
 	return value
 }
 
 // Generar registros adicionales basados en el dataset existente
-func generateAdditionalRecords(existingRecords []Record, num int) []Record {
+func generateAdditionalRecords(existingRecords []Record, num int, rng *rand.Rand) []Record {
 	var newRecords []Record
-	rand.Seed(time.Now().UnixNano())
 
 	for i := 0; i < num; i++ {
-		original := existingRecords[rand.Intn(len(existingRecords))]
+		original := existingRecords[rng.Intn(len(existingRecords))]
 		newRecord := Record{
-			Age:           original.Age + rand.Intn(10) - 5, // Variar la edad un poco
+			Age:           original.Age + rng.Intn(10) - 5, // Variar la edad un poco
 			WorkClass:     original.WorkClass,
-			Fnlwgt:        original.Fnlwgt + rand.Intn(5000) - 2500,
+			Fnlwgt:        original.Fnlwgt + rng.Intn(5000) - 2500,
 			Education:     original.Education,
 			EducationNum:  original.EducationNum,
 			MaritalStatus: original.MaritalStatus,
@@ -126,9 +160,9 @@ func generateAdditionalRecords(existingRecords []Record, num int) []Record {
 			Relationship:  original.Relationship,
 			Race:          original.Race,
 			Sex:           original.Sex,
-			CapitalGain:   original.CapitalGain + rand.Intn(1000) - 500,
-			CapitalLoss:   original.CapitalLoss + rand.Intn(500) - 250,
-			HoursPerWeek:  original.HoursPerWeek + rand.Intn(10) - 5,
+			CapitalGain:   original.CapitalGain + rng.Intn(1000) - 500,
+			CapitalLoss:   original.CapitalLoss + rng.Intn(500) - 250,
+			HoursPerWeek:  original.HoursPerWeek + rng.Intn(10) - 5,
 			NativeCountry: original.NativeCountry,
 			Income:        original.Income,
 		}
@@ -137,6 +171,26 @@ func generateAdditionalRecords(existingRecords []Record, num int) []Record {
 	return newRecords
 }
 
+// Separar los registros en entrenamiento y validación (la fracción indicada) con una permutación del flujo
+// reservado de la semilla, independiente del resto de flujos de los modelos; sin validación si la fracción no deja ningún registro en alguno de los dos conjuntos
+func SplitValidation(records []Record, fraction float64, seed int64) (train, validation []Record) {
+	numValidation := int(float64(len(records)) * fraction)
+	if numValidation <= 0 || numValidation >= len(records) {
+		return records, nil
+	}
+	perm := random.Derive(seed, random.ValidationStream).Perm(len(records))
+	train = make([]Record, 0, len(records)-numValidation)
+	validation = make([]Record, 0, numValidation)
+	for i, j := range perm {
+		if i < numValidation {
+			validation = append(validation, records[j])
+		} else {
+			train = append(train, records[j])
+		}
+	}
+	return train, validation
+}
+
 // Función para imprimir los registros (opcional para debug)
 func PrintRecords(records []Record) {
 	for _, record := range records {
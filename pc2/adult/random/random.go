@@ -0,0 +1,29 @@
+package random
+
+import "math/rand"
+
+// Constante de SplitMix64 para separar flujos consecutivos
+const golden = 0x9e3779b97f4a7c15
+
+// Crear un generador propio a partir de una semilla (no usa la fuente global)
+func New(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Flujo reservado para separar los registros de validación; los árboles, las rondas, las épocas y las
+// características usan flujos no negativos, así que nunca coinciden con él
+const ValidationStream = -1
+
+// Derivar un generador independiente para el flujo número stream (un árbol, un worker, etc.)
+// a partir de la semilla principal, de forma que el resultado no dependa del orden de ejecución.
+// Los flujos negativos están reservados (ValidationStream)
+func Derive(seed int64, stream int) *rand.Rand {
+	return New(int64(splitMix64(uint64(seed) + uint64(stream+1)*golden)))
+}
+
+// Función de mezcla de SplitMix64
+func splitMix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
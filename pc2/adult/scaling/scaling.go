@@ -0,0 +1,225 @@
+package scaling
+
+import (
+	"adult/preprocess"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Método de escalado de las características
+type Method int
+
+const (
+	ZScore Method = iota // (x - media) / desviación estándar
+	MinMax               // (x - mínimo) / (máximo - mínimo)
+	Robust               // (x - mediana) / rango intercuartílico
+)
+
+// Máximo de filas usadas para estimar los cuantiles del escalado robusto
+const robustSampleSize = 100000
+
+// Escalador ajustado con los datos de entrenamiento: x' = (x - Center) / Scale
+type Scaler struct {
+	Method Method
+	Center []float64
+	Scale  []float64
+}
+
+// Estadísticas parciales de un bloque de registros
+type chunkStats struct {
+	count int
+	mean  []float64
+	m2    []float64 // Suma de cuadrados de las diferencias con la media (Welford)
+	min   []float64
+	max   []float64
+}
+
+// Ajustar el escalador con los vectores que produce transform para cada registro,
+// repartiendo los registros en bloques que se procesan en paralelo; sin registros devuelve
+// la identidad con tantas columnas como el vector de un registro vacío
+func Fit(records []preprocess.Record, transform func(preprocess.Record) []float64, method Method, workers int) *Scaler {
+	if len(records) == 0 {
+		return identity(method, len(transform(preprocess.Record{})))
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	numFeatures := len(transform(records[0]))
+
+	if method == Robust {
+		return fitRobust(records, transform, numFeatures, workers)
+	}
+
+	// Cada worker calcula las estadísticas de su bloque
+	chunkSize := (len(records) + workers - 1) / workers
+	partials := make([]*chunkStats, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunkSize
+		end := min(start+chunkSize, len(records))
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func(w int, chunk []preprocess.Record) {
+			defer wg.Done()
+			partials[w] = computeStats(chunk, transform, numFeatures)
+		}(w, records[start:end])
+	}
+	wg.Wait()
+
+	// Combinar los resultados parciales
+	var total *chunkStats
+	for _, partial := range partials {
+		if partial == nil {
+			continue
+		}
+		if total == nil {
+			total = partial
+		} else {
+			total.merge(partial)
+		}
+	}
+
+	scaler := &Scaler{
+		Method: method,
+		Center: make([]float64, numFeatures),
+		Scale:  make([]float64, numFeatures),
+	}
+	for j := 0; j < numFeatures; j++ {
+		switch method {
+		case ZScore:
+			scaler.Center[j] = total.mean[j]
+			scaler.Scale[j] = math.Sqrt(total.m2[j] / float64(total.count))
+		case MinMax:
+			scaler.Center[j] = total.min[j]
+			scaler.Scale[j] = total.max[j] - total.min[j]
+		}
+	}
+	scaler.fixZeroScales()
+	return scaler
+}
+
+// Calcular media, varianza, mínimo y máximo de un bloque (algoritmo de Welford)
+func computeStats(records []preprocess.Record, transform func(preprocess.Record) []float64, numFeatures int) *chunkStats {
+	stats := &chunkStats{
+		mean: make([]float64, numFeatures),
+		m2:   make([]float64, numFeatures),
+		min:  make([]float64, numFeatures),
+		max:  make([]float64, numFeatures),
+	}
+	for j := range stats.min {
+		stats.min[j] = math.Inf(1)
+		stats.max[j] = math.Inf(-1)
+	}
+
+	for _, record := range records {
+		features := transform(record)
+		stats.count++
+		for j, x := range features {
+			delta := x - stats.mean[j]
+			stats.mean[j] += delta / float64(stats.count)
+			stats.m2[j] += delta * (x - stats.mean[j])
+			stats.min[j] = math.Min(stats.min[j], x)
+			stats.max[j] = math.Max(stats.max[j], x)
+		}
+	}
+	return stats
+}
+
+// Combinar las estadísticas de otro bloque (fórmula de Chan et al.)
+func (s *chunkStats) merge(other *chunkStats) {
+	total := s.count + other.count
+	for j := range s.mean {
+		delta := other.mean[j] - s.mean[j]
+		s.m2[j] += other.m2[j] + delta*delta*float64(s.count)*float64(other.count)/float64(total)
+		s.mean[j] += delta * float64(other.count) / float64(total)
+		s.min[j] = math.Min(s.min[j], other.min[j])
+		s.max[j] = math.Max(s.max[j], other.max[j])
+	}
+	s.count = total
+}
+
+// Ajustar el escalado robusto con la mediana y el rango intercuartílico de cada columna,
+// calculando los cuantiles de las columnas en paralelo
+func fitRobust(records []preprocess.Record, transform func(preprocess.Record) []float64, numFeatures int, workers int) *Scaler {
+	// Tomar una muestra uniforme de filas para no guardar todo el dataset en memoria
+	step := max(1, len(records)/robustSampleSize)
+	columns := make([][]float64, numFeatures)
+	for i := 0; i < len(records); i += step {
+		for j, x := range transform(records[i]) {
+			columns[j] = append(columns[j], x)
+		}
+	}
+
+	scaler := &Scaler{
+		Method: Robust,
+		Center: make([]float64, numFeatures),
+		Scale:  make([]float64, numFeatures),
+	}
+
+	columnChan := make(chan int, numFeatures)
+	for j := 0; j < numFeatures; j++ {
+		columnChan <- j
+	}
+	close(columnChan)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range columnChan {
+				values := columns[j]
+				sort.Float64s(values)
+				scaler.Center[j] = quantile(values, 0.5)
+				scaler.Scale[j] = quantile(values, 0.75) - quantile(values, 0.25)
+			}
+		}()
+	}
+	wg.Wait()
+
+	scaler.fixZeroScales()
+	return scaler
+}
+
+// Cuantil q de valores ordenados (interpolación lineal)
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return sorted[lower]*(1-frac) + sorted[upper]*frac
+}
+
+// Escalador que no modifica los vectores (centro 0 y escala 1 en cada columna)
+func identity(method Method, numFeatures int) *Scaler {
+	scaler := &Scaler{
+		Method: method,
+		Center: make([]float64, numFeatures),
+		Scale:  make([]float64, numFeatures),
+	}
+	for j := range scaler.Scale {
+		scaler.Scale[j] = 1
+	}
+	return scaler
+}
+
+// Evitar divisiones por cero en columnas constantes
+func (s *Scaler) fixZeroScales() {
+	for j, scale := range s.Scale {
+		if scale == 0 || math.IsNaN(scale) {
+			s.Scale[j] = 1
+		}
+	}
+}
+
+// Escalar un vector de características (devuelve un vector nuevo)
+func (s *Scaler) Transform(features []float64) []float64 {
+	scaled := make([]float64, len(features))
+	for j, x := range features {
+		scaled[j] = (x - s.Center[j]) / s.Scale[j]
+	}
+	return scaled
+}
@@ -1,257 +1,450 @@
 package concurrent
 
 import (
-	"ann/preprocess"
+	"adult/atomicfloat"
+	"adult/classifier"
+	"adult/encoding"
+	"adult/metrics"
+	"adult/persist"
+	"adult/preprocess"
+	"adult/random"
+	"adult/scaling"
+	"ann/network"
+	"ann/optimizer"
 	"fmt"
-	"math"
-	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// La red implementa la interfaz común de clasificadores
+var _ classifier.Classifier = (*NeuralNetwork)(nil)
+
+// Valores por defecto de Fit
+const (
+	defaultEpochs    = 50
+	defaultBatchSize = 64
+	defaultPatience  = 5
+	defaultWorkers   = 4
+)
+
 // Estructura de la red neuronal concurrente
 type NeuralNetwork struct {
-	InputNeurons  int
-	HiddenNeurons int
-	OutputNeurons int
-	LearningRate  float64
-	WeightsIH     [][]float64 // Pesos entre la capa de entrada y la oculta
-	WeightsHO     []float64   // Pesos entre la capa oculta y la de salida
-	BiasH         []float64   // Sesgo para las neuronas ocultas
-	BiasO         float64     // Sesgo para la neurona de salida
-	mu            sync.Mutex  // Mutex para evitar condición de carrera
+	InputNeurons       int
+	Architecture       network.Architecture // Capas ocultas y neuronas de salida
+	LearningRate       float64
+	Epochs             int               // Número de épocas que usa Fit
+	BatchSize          int               // Registros por mini-lote (<= 0 para usar todos en cada paso)
+	Optimizer          optimizer.Config  // Método de actualización de los pesos y sus hiperparámetros
+	Seed               int64             // Semilla del barajado de los registros en cada época y de la validación
+	ValidationFraction float64           // Fracción de registros que Fit reserva para la validación y la parada temprana (0 para no reservar)
+	Patience           int               // Épocas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	BestEpoch          int               // Época cuyos pesos se conservaron (la de menor pérdida de validación; 0 sin validación)
+	Workers            int               // Número de goroutines que usan Fit y PredictBatch (el entrenamiento síncrono usa como mucho network.GradientShards)
+	Hogwild            bool              // Entrenar de forma asíncrona sin bloqueos (Hogwild!) en lugar de síncrona
+	Layers             network.Layers    // Capas densas, de la entrada a la salida
+	Encoder            *encoding.Encoder // Codificador de características ajustado con los datos de entrenamiento
+	Scaler             *scaling.Scaler   // Escalador ajustado con los datos de entrenamiento (nil para no escalar)
+
+	observers []metrics.Observer // Reciben las métricas de cada época (no se guardan con el modelo)
 }
 
-// Función para crear y inicializar una red neuronal
-func NewNeuralNetwork(inputNeurons, hiddenNeurons, outputNeurons int, learningRate float64) *NeuralNetwork {
-	rand.Seed(time.Now().UnixNano())
+// Función para crear y inicializar una red neuronal con la arquitectura dada (una neurona de entrada por cada característica del codificador)
+func NewNeuralNetwork(encoder *encoding.Encoder, scaler *scaling.Scaler, arch network.Architecture, learningRate float64, seed int64) *NeuralNetwork {
+	rng := random.New(seed)
+	inputNeurons := encoder.NumFeatures()
 	nn := &NeuralNetwork{
-		InputNeurons:  inputNeurons,
-		HiddenNeurons: hiddenNeurons,
-		OutputNeurons: outputNeurons,
-		LearningRate:  learningRate,
-		WeightsIH:     make([][]float64, hiddenNeurons),
-		WeightsHO:     make([]float64, hiddenNeurons),
-		BiasH:         make([]float64, hiddenNeurons),
-		BiasO:         rand.Float64(),
+		InputNeurons: inputNeurons,
+		Architecture: arch,
+		LearningRate: learningRate,
+		Epochs:       defaultEpochs,
+		BatchSize:    defaultBatchSize,
+		Patience:     defaultPatience,
+		Optimizer:    optimizer.Config{Method: optimizer.Adam},
+		Seed:         seed,
+		Workers:      defaultWorkers,
+		Layers:       network.New(inputNeurons, arch, rng),
+		Encoder:      encoder,
+		Scaler:       scaler,
 	}
+	return nn
+}
 
-	// Inicializar los pesos aleatoriamente
-	for i := range nn.WeightsIH {
-		nn.WeightsIH[i] = make([]float64, inputNeurons)
-		for j := range nn.WeightsIH[i] {
-			nn.WeightsIH[i][j] = rand.Float64() * 0.1
-		}
-		nn.WeightsHO[i] = rand.Float64() * 0.1
-		nn.BiasH[i] = rand.Float64() * 0.1
-	}
+// Añadir un observador que recibe las métricas de cada época del entrenamiento
+func (nn *NeuralNetwork) AddObserver(observer metrics.Observer) {
+	nn.observers = append(nn.observers, observer)
+}
 
-	return nn
+// Forward pass y retropropagación de un registro con las capas dadas (las de la red o la copia local de
+// un worker): suma a g el gradiente de su pérdida
+func (nn *NeuralNetwork) backward(layers network.Layers, record preprocess.Record, g *network.Gradients) {
+	activations, netInputs := layers.Forward(nn.extractFeatures(record))
+	layers.Backward(activations, netInputs, nn.target(record), g)
+}
+
+// Salida esperada para un registro: la etiqueta 0/1 con una neurona de salida, o la clase en one-hot
+// (0 = NegativeLabel, 1 = PositiveLabel) con una neurona por clase
+func (nn *NeuralNetwork) target(record preprocess.Record) []float64 {
+	label := convertLabel(record.Income)
+	outputs := nn.Layers[len(nn.Layers)-1].Outputs()
+	if outputs == 1 {
+		return []float64{label}
+	}
+	target := make([]float64, outputs)
+	target[int(label)] = 1
+	return target
 }
 
-// Función de activación (sigmoide)
-func sigmoid(x float64) float64 {
-	return 1.0 / (1.0 + math.Exp(-x))
+// Aplicar un paso del optimizador con los gradientes promediados del mini-lote
+func (nn *NeuralNetwork) step(opt *optimizer.Optimizer, g *network.Gradients) {
+	opt.Step(nn.Layers.Parameters(), g.Groups())
 }
 
-// Derivada de la función sigmoide
-func sigmoidDerivative(x float64) float64 {
-	return x * (1 - x)
+// Tamaño de mini-lote efectivo (todo el conjunto si BatchSize <= 0)
+func (nn *NeuralNetwork) batchSize(numRecords int) int {
+	if nn.BatchSize <= 0 || nn.BatchSize > numRecords {
+		return numRecords
+	}
+	return nn.BatchSize
 }
 
-// Función para entrenar la red neuronal concurrentemente con optimizaciones
+// Función para entrenar la red neuronal por mini-lotes con paralelismo de datos síncrono: en cada época se
+// barajan los registros con un generador derivado de la semilla; cada lote se divide en fragmentos que se
+// reparten en bloques contiguos entre los workers, los gradientes se suman con una reducción en árbol sin
+// mutex y se aplica un paso del optimizador con el gradiente medio. Como cada worker procesa al menos un
+// fragmento, se usan como mucho network.GradientShards (16) workers
 func (nn *NeuralNetwork) Train(records []preprocess.Record, epochs int, workers int) {
-	var wg sync.WaitGroup
-	recordChan := make(chan preprocess.Record, len(records))
-	workerChan := make(chan int, workers) // Limitar el número de workers concurrentes
+	nn.train(records, nil, epochs, workers)
+}
+
+// Entrenar con los registros e informar a los observadores al final de cada época de la pérdida media de
+// entrenamiento y, si hay registros de validación, de la pérdida y la precisión sobre ellos. Con validación,
+// el entrenamiento para tras nn.Patience épocas sin mejorar la pérdida y conserva los mejores pesos
+func (nn *NeuralNetwork) train(records, validation []preprocess.Record, epochs int, workers int) {
+	if len(records) == 0 {
+		return
+	}
+	if nn.Hogwild {
+		nn.trainHogwild(records, validation, epochs, max(1, workers))
+		return
+	}
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(nn.Patience)
+	workers = max(1, min(workers, network.GradientShards))
+	opt := optimizer.New(nn.Optimizer, nn.LearningRate)
+	batchSize := nn.batchSize(len(records))
+	shards := make([]*network.Gradients, network.GradientShards)
+	for k := range shards {
+		shards[k] = network.NewGradients(nn.Layers)
+	}
+	pending := make([]atomic.Int32, network.GradientShards) // Hijos pendientes de cada nodo interno del árbol (el 1 es la raíz)
+
+	// Workers que duran todo el entrenamiento: en cada paso reciben el mini-lote y procesan siempre el mismo
+	// bloque contiguo de fragmentos
+	batches := make([]chan []int, workers)
+	var done sync.WaitGroup // Workers que aún no han terminado el paso actual
+	for w := range batches {
+		batches[w] = make(chan []int)
+		go func(w int) {
+			for batch := range batches[w] {
+				for k := w * network.GradientShards / workers; k < (w+1)*network.GradientShards/workers; k++ {
+					shards[k].Reset()
+					for _, index := range batch[k*len(batch)/network.GradientShards : (k+1)*len(batch)/network.GradientShards] {
+						nn.backward(nn.Layers, records[index], shards[k])
+					}
+					reduceUp(shards, pending, k)
+				}
+				done.Done()
+			}
+		}(w)
+	}
+	defer func() {
+		for _, worker := range batches {
+			close(worker)
+		}
+	}()
 
 	for epoch := 0; epoch < epochs; epoch++ {
-		gradientsIH := make([][]float64, nn.HiddenNeurons) // Gradientes acumulados para IH
-		gradientsHO := make([]float64, nn.HiddenNeurons)   // Gradientes acumulados para HO
-		biasGradientsH := make([]float64, nn.HiddenNeurons)
-		biasGradientO := 0.0
+		order := random.Derive(nn.Seed, epoch).Perm(len(records))
+		loss := 0.0
+		for first := 0; first < len(order); first += batchSize {
+			batch := order[first:min(first+batchSize, len(order))]
+			for node := 1; node < network.GradientShards; node++ {
+				pending[node].Store(2)
+			}
+			done.Add(workers)
+			for _, worker := range batches {
+				worker <- batch
+			}
+			done.Wait()
 
-		for i := range gradientsIH {
-			gradientsIH[i] = make([]float64, nn.InputNeurons)
+			loss += shards[0].Loss
+			shards[0].Scale(1 / float64(len(batch)))
+			nn.step(opt, shards[0])
 		}
+		m := metrics.Report(nn.observers, epoch+1, loss/float64(len(records)), start, validation, nn.Evaluate)
+		if len(validation) > 0 && stopping.Check(m, nn.snapshot) {
+			break
+		}
+	}
+	nn.BestEpoch = stopping.Restore()
+}
 
-		// Crear los workers para procesar los registros concurrentemente
-		for w := 0; w < workers; w++ {
-			workerChan <- w
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-
-				// Gradientes locales en cada goroutine
-				localGradientsIH := make([][]float64, nn.HiddenNeurons)
-				localGradientsHO := make([]float64, nn.HiddenNeurons)
-				localBiasGradientsH := make([]float64, nn.HiddenNeurons)
-				localBiasGradientO := 0.0
+// Estado propio de un worker en el entrenamiento Hogwild!
+type hogwildWorker struct {
+	layers network.Layers       // Copia local de las capas, refrescada antes de cada mini-lote
+	grads  *network.Gradients   // Gradiente medio del mini-lote
+	deltas *network.Gradients   // Incremento que el optimizador calcula para cada parámetro
+	opt    *optimizer.Optimizer // Optimizador con el estado (momentos, velocidad) de este worker
+	loss   float64              // Pérdida total de los lotes de este worker en la época
+}
 
-				for i := range localGradientsIH {
-					localGradientsIH[i] = make([]float64, nn.InputNeurons)
-				}
+// Entrenamiento asíncrono sin bloqueos (Hogwild!): los mini-lotes de cada época se reparten entre los
+// workers, y cada uno, sin esperar a los demás, copia los pesos compartidos con lecturas atómicas, calcula
+// el gradiente medio de su lote, obtiene el incremento con su propio optimizador y lo suma a los pesos
+// compartidos de forma atómica. El resultado depende del orden en que se intercalan los workers
+func (nn *NeuralNetwork) trainHogwild(records, validation []preprocess.Record, epochs int, workers int) {
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(nn.Patience)
+	batchSize := nn.batchSize(len(records))
+	shared := nn.Layers.Parameters()
+	states := make([]*hogwildWorker, workers)
+	for w := range states {
+		states[w] = &hogwildWorker{
+			layers: nn.Layers.Clone(),
+			grads:  network.NewGradients(nn.Layers),
+			deltas: network.NewGradients(nn.Layers),
+			opt:    optimizer.New(nn.Optimizer, nn.LearningRate),
+		}
+	}
 
-				for record := range recordChan {
-					features := extractFeatures(record)
-					label := convertLabel(record.Income)
-
-					// Forward pass
-					hiddenOutputs := make([]float64, nn.HiddenNeurons)
-					for i := 0; i < nn.HiddenNeurons; i++ {
-						sum := nn.BiasH[i]
-						for j := 0; j < nn.InputNeurons; j++ {
-							sum += nn.WeightsIH[i][j] * features[j]
-						}
-						hiddenOutputs[i] = sigmoid(sum)
+	for epoch := 0; epoch < epochs; epoch++ {
+		order := random.Derive(nn.Seed, epoch).Perm(len(records))
+		numBatches := (len(order) + batchSize - 1) / batchSize
+		var wg sync.WaitGroup
+		for w, state := range states {
+			wg.Add(1)
+			go func(w int, state *hogwildWorker) {
+				defer wg.Done()
+				local, grads, deltas := state.layers.Parameters(), state.grads.Groups(), state.deltas.Groups()
+				state.loss = 0
+				for b := w; b < numBatches; b += workers {
+					batch := order[b*batchSize : min((b+1)*batchSize, len(order))]
+					for g := range shared {
+						atomicfloat.LoadSlice(local[g], shared[g])
 					}
-
-					finalInput := nn.BiasO
-					for i := 0; i < nn.HiddenNeurons; i++ {
-						finalInput += nn.WeightsHO[i] * hiddenOutputs[i]
+					state.grads.Reset()
+					for _, index := range batch {
+						nn.backward(state.layers, records[index], state.grads)
 					}
-					finalOutput := sigmoid(finalInput)
-
-					// Backpropagation
-					outputError := label - finalOutput
-					gradient := outputError * sigmoidDerivative(finalOutput)
-
-					// Actualizar gradientes locales
-					for i := 0; i < nn.HiddenNeurons; i++ {
-						deltaHO := gradient * hiddenOutputs[i]
-						localGradientsHO[i] += deltaHO
+					state.loss += state.grads.Loss
+					state.grads.Scale(1 / float64(len(batch)))
+
+					// El optimizador resta su paso a unos incrementos a cero: quedan los incrementos a aplicar
+					state.deltas.Reset()
+					state.opt.Step(deltas, grads)
+					for g := range shared {
+						atomicfloat.AddSlice(shared[g], deltas[g])
 					}
-					localBiasGradientO += gradient
+				}
+			}(w, state)
+		}
+		wg.Wait()
 
-					// Backpropagation para la capa oculta
-					for i := 0; i < nn.HiddenNeurons; i++ {
-						hiddenError := gradient * nn.WeightsHO[i]
-						hiddenGradient := hiddenError * sigmoidDerivative(hiddenOutputs[i])
+		loss := 0.0
+		for _, state := range states {
+			loss += state.loss
+		}
+		m := metrics.Report(nn.observers, epoch+1, loss/float64(len(records)), start, validation, nn.Evaluate)
+		if len(validation) > 0 && stopping.Check(m, nn.snapshot) {
+			break
+		}
+	}
+	nn.BestEpoch = stopping.Restore()
+}
 
-						for j := 0; j < nn.InputNeurons; j++ {
-							localGradientsIH[i][j] += hiddenGradient * features[j]
-						}
-						localBiasGradientsH[i] += hiddenGradient
-					}
-				}
+// Función para copiar las capas y devolver la función que las restaura (parada temprana)
+func (nn *NeuralNetwork) snapshot() func() {
+	layers := nn.Layers.Clone()
+	return func() { nn.Layers = layers }
+}
 
-				// Aplicar los gradientes locales acumulados a los gradientes globales
-				nn.mu.Lock()
-				for i := 0; i < nn.HiddenNeurons; i++ {
-					gradientsHO[i] += localGradientsHO[i]
-					biasGradientsH[i] += localBiasGradientsH[i]
-					for j := 0; j < nn.InputNeurons; j++ {
-						gradientsIH[i][j] += localGradientsIH[i][j]
+// Función para calcular la entropía cruzada media y la precisión de la red sobre los registros. Los
+// fragmentos se reparten entre los workers y sus sumas se combinan en orden, con el mismo resultado que
+// la versión secuencial
+func (nn *NeuralNetwork) Evaluate(records []preprocess.Record) (loss, accuracy float64) {
+	workers := max(1, min(nn.Workers, network.GradientShards))
+	shardLoss := make([]float64, network.GradientShards)
+	shardCorrect := make([]int, network.GradientShards)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for k := w * network.GradientShards / workers; k < (w+1)*network.GradientShards/workers; k++ {
+				for _, record := range records[k*len(records)/network.GradientShards : (k+1)*len(records)/network.GradientShards] {
+					outputs := nn.PredictOutputs(record)
+					shardLoss[k] += network.CrossEntropy(outputs, nn.target(record))
+					if classifier.LabelFromProba(positiveProba(outputs)) == record.Income {
+						shardCorrect[k]++
 					}
 				}
-				biasGradientO += localBiasGradientO
-				nn.mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
 
-				<-workerChan // Liberar el worker para la próxima tarea
-			}()
-		}
+	correct := 0
+	for k := range shardLoss {
+		loss += shardLoss[k]
+		correct += shardCorrect[k]
+	}
+	return loss / float64(len(records)), float64(correct) / float64(len(records))
+}
 
-		// Enviar registros a las goroutines
-		for _, record := range records {
-			recordChan <- record
+// Subir por el árbol de reducción desde el fragmento k recién calculado. En cada nodo interno, el segundo
+// de sus dos hijos en terminar suma el gradiente del subárbol derecho en el del izquierdo (guardado en el
+// primer fragmento de su rango) y sigue subiendo; el primero se detiene. Los contadores atómicos ordenan
+// las escrituras de un worker antes de las lecturas del otro, así que no hace falta ningún mutex
+func reduceUp(shards []*network.Gradients, pending []atomic.Int32, k int) {
+	span := 1 // Fragmentos que cubre cada hijo del nodo actual
+	for node := (len(shards) + k) / 2; node >= 1; node /= 2 {
+		if pending[node].Add(-1) != 0 {
+			return // El otro hijo aún no ha terminado: su worker hará la suma
 		}
+		left := k &^ (2*span - 1)
+		shards[left].Add(shards[left+span])
+		span *= 2
+	}
+}
 
-		// Cerrar el canal y esperar a que los workers terminen
-		close(recordChan)
-		wg.Wait()
+// Función para calcular las salidas de la red para un registro (con softmax, la probabilidad de cada clase)
+func (nn *NeuralNetwork) PredictOutputs(record preprocess.Record) []float64 {
+	return nn.Layers.Output(nn.extractFeatures(record))
+}
 
-		// Actualizar los pesos y sesgos después de procesar todos los registros
-		nn.mu.Lock()
-		for i := 0; i < nn.HiddenNeurons; i++ {
-			nn.WeightsHO[i] += nn.LearningRate * gradientsHO[i]
-			nn.BiasH[i] += nn.LearningRate * biasGradientsH[i]
-			for j := 0; j < nn.InputNeurons; j++ {
-				nn.WeightsIH[i][j] += nn.LearningRate * gradientsIH[i][j]
-			}
-		}
-		nn.BiasO += nn.LearningRate * biasGradientO
-		nn.mu.Unlock()
+// Función para estimar la probabilidad de la clase positiva (salida sigmoide, o salida softmax de la clase positiva; sin concurrencia)
+func (nn *NeuralNetwork) PredictProba(record preprocess.Record) float64 {
+	return positiveProba(nn.PredictOutputs(record))
+}
 
-		// Resetear los canales para la próxima época
-		recordChan = make(chan preprocess.Record, len(records))
+// Probabilidad de la clase positiva a partir de las salidas de la red
+func positiveProba(outputs []float64) float64 {
+	if len(outputs) == 1 {
+		return outputs[0]
 	}
+	return outputs[1]
 }
 
-// Función para predecir usando la red neuronal (sin concurrencia)
-func (nn *NeuralNetwork) Predict(record preprocess.Record) float64 {
-	features := extractFeatures(record)
-	hiddenOutputs := make([]float64, nn.HiddenNeurons)
-
-	// Cálculo de la capa oculta
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		sum := nn.BiasH[i]
-		for j := 0; j < nn.InputNeurons; j++ {
-			sum += nn.WeightsIH[i][j] * features[j]
-		}
-		hiddenOutputs[i] = sigmoid(sum)
+// Función para entrenar la red con los registros, reservando nn.ValidationFraction de ellos para las
+// métricas de validación (implementa classifier.Classifier)
+func (nn *NeuralNetwork) Fit(records []preprocess.Record) error {
+	if len(records) == 0 {
+		return classifier.ErrNoRecords
 	}
-
-	// Cálculo de la salida final
-	finalInput := nn.BiasO
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		finalInput += nn.WeightsHO[i] * hiddenOutputs[i]
+	if err := nn.Layers.Validate(nn.InputNeurons); err != nil {
+		return err
 	}
-	finalOutput := sigmoid(finalInput)
+	train, validation := preprocess.SplitValidation(records, nn.ValidationFraction, nn.Seed)
+	nn.train(train, validation, nn.Epochs, nn.Workers)
+	return nil
+}
+
+// Función para predecir la etiqueta del registro
+func (nn *NeuralNetwork) Predict(record preprocess.Record) string {
+	return classifier.LabelFromProba(nn.PredictProba(record))
+}
 
-	return finalOutput
+// Función para predecir las etiquetas de varios registros repartidos entre los workers
+func (nn *NeuralNetwork) PredictBatch(records []preprocess.Record) []string {
+	return classifier.BatchPredict(nn.Predict, records, nn.Workers)
 }
 
-// Función para extraer características numéricas del registro
-func extractFeatures(record preprocess.Record) []float64 {
-	return []float64{
-		float64(record.Age),
-		float64(record.Fnlwgt),
-		float64(record.EducationNum),
-		float64(record.CapitalGain),
-		float64(record.CapitalLoss),
-		float64(record.HoursPerWeek),
+// Función para extraer las características (numéricas y categóricas codificadas) del registro
+func (nn *NeuralNetwork) extractFeatures(record preprocess.Record) []float64 {
+	features := nn.Encoder.Transform(record)
+	if nn.Scaler != nil {
+		features = nn.Scaler.Transform(features)
 	}
+	return features
 }
 
 // Función para convertir la etiqueta de ingreso a 0 o 1
 func convertLabel(income string) float64 {
-	if income == ">50K" {
+	if income == preprocess.PositiveLabel {
 		return 1.0
 	}
 	return 0.0
 }
 
-// Función para probar la red neuronal concurrente
-func TestConcurrentNN(records []preprocess.Record) {
-	// Dividir datos en entrenamiento y prueba (80% entrenamiento, 20% prueba)
-	numTrain := int(0.8 * float64(len(records)))
-	trainData := records[:numTrain]
-	testData := records[numTrain:]
+// Tipo de modelo en los archivos guardados (el mismo que en la versión secuencial, son intercambiables)
+const modelKind = "ann"
+
+// Versión del formato de la red en disco (2: lista de capas en lugar de una sola capa oculta)
+const modelVersion = 2
+
+// Guardar la red (pesos, codificador y escalador) en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func (nn *NeuralNetwork) Save(path string) error {
+	return persist.Save(path, modelKind, modelVersion, nn)
+}
+
+// Cargar un modelo guardado con Save
+func Load(path string) (*NeuralNetwork, error) {
+	nn := &NeuralNetwork{}
+	if err := persist.Load(path, modelKind, modelVersion, nn); err != nil {
+		return nil, err
+	}
+	if err := nn.Layers.Validate(nn.InputNeurons); err != nil {
+		return nil, fmt.Errorf("modelo no válido: %v", err)
+	}
+	if nn.Workers <= 0 {
+		nn.Workers = defaultWorkers
+	}
+	return nn, nil
+}
 
+// Configuración del entrenamiento de TestConcurrentNN
+type Config struct {
+	Architecture       network.Architecture // Capas ocultas y neuronas de salida
+	LearningRate       float64              // Tasa de aprendizaje del optimizador
+	BatchSize          int                  // Registros por mini-lote (<= 0 para usar todos en cada paso)
+	Optimizer          optimizer.Config     // Método de actualización de los pesos y sus hiperparámetros
+	Epochs             int                  // Número máximo de épocas
+	Patience           int                  // Épocas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	ValidationFraction float64              // Fracción de los registros reservada para la validación (0 para no reservar)
+	Observer           metrics.Observer     // Recibe las métricas de cada época (nil para no informar)
+}
+
+// Función para probar la red neuronal concurrente (devuelve el modelo entrenado)
+func TestConcurrentNN(trainData, testData []preprocess.Record, seed int64, config Config) *NeuralNetwork {
 	// Crear y entrenar la red neuronal concurrente
 	fmt.Println("Entrenando Red Neuronal Concurrente...")
-	nn := NewNeuralNetwork(6, 10, 1, 0.01) // 6 neuronas de entrada, 10 ocultas, 1 de salida, tasa de aprendizaje 0.01
+	encoder := encoding.Fit(trainData, encoding.OneHot)
+	scaler := scaling.Fit(trainData, encoder.Transform, scaling.ZScore, 4)
+	nn := NewNeuralNetwork(encoder, scaler, config.Architecture, config.LearningRate, seed) // Entradas según el codificador
+	nn.BatchSize, nn.Optimizer = config.BatchSize, config.Optimizer
+	nn.Epochs, nn.Patience, nn.ValidationFraction = config.Epochs, config.Patience, config.ValidationFraction
+	if config.Observer != nil {
+		nn.AddObserver(config.Observer)
+	}
+	fmt.Printf("Capas ocultas: %v (activaciones %v), neuronas de salida: %d, inicialización %v\n", config.Architecture.Hidden, config.Architecture.Activations, config.Architecture.Outputs, config.Architecture.Initializers)
+	fmt.Printf("Optimizador: %s, tasa de aprendizaje %g, mini-lotes de %d registros\n", config.Optimizer.Method, config.LearningRate, config.BatchSize)
 
 	start := time.Now()
-	nn.Train(trainData, 50, 4) // Entrenamiento con 50 épocas y 4 workers
+	if err := nn.Fit(trainData); err != nil { // Entrenamiento con 4 workers
+		fmt.Printf("Error al entrenar: %v\n", err)
+		return nil
+	}
 	elapsed := time.Since(start)
 	fmt.Printf("Tiempo de entrenamiento: %s\n", elapsed)
+	if nn.BestEpoch > 0 {
+		fmt.Printf("Parada temprana: pesos de la época %d (de %d como máximo)\n", nn.BestEpoch, config.Epochs)
+	}
 
 	// Probar el modelo
 	fmt.Println("Probando Red Neuronal Concurrente...")
-	correct := 0
-	for _, record := range testData {
-		output := nn.Predict(record)
-		prediction := 0.0
-		if output > 0.5 {
-			prediction = 1.0
-		}
-		label := convertLabel(record.Income)
-		if prediction == label {
-			correct++
-		}
-	}
-
-	accuracy := float64(correct) / float64(len(testData))
+	accuracy := classifier.Accuracy(nn, testData)
 	fmt.Printf("Precisión: %.2f%%\n", accuracy*100)
+
+	return nn
 }
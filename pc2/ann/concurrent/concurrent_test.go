@@ -0,0 +1,138 @@
+package concurrent
+
+import (
+	"adult/encoding"
+	"adult/preprocess"
+	"adult/scaling"
+	"ann/network"
+	"ann/optimizer"
+	"ann/sequential"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Semilla fija de las pruebas
+const testSeed = 42
+
+// Registros de entrenamiento de las pruebas (los primeros del archivo, sin aumentar)
+const testRecords = 2000
+
+// Con la misma semilla el entrenamiento síncrono concurrente debe dejar exactamente los mismos pesos,
+// la misma época de parada y las mismas predicciones que el secuencial, con cualquier número de workers
+func TestNeuralNetworkMatchesSequential(t *testing.T) {
+	records, err := preprocess.ReadRecords("../adult.data", testRecords)
+	if err != nil {
+		t.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.OneHot)
+	scaler := scaling.Fit(records, encoder.Transform, scaling.ZScore, 1)
+
+	tests := []struct {
+		name       string
+		arch       network.Architecture
+		opt        optimizer.Config
+		batchSize  int
+		validation float64
+		patience   int
+	}{
+		{"sgd", network.Architecture{Hidden: []int{8}, Outputs: 1}, optimizer.Config{Method: optimizer.SGD}, 32, 0, 0},
+		{"nesterov softmax", network.Architecture{Hidden: []int{8}, Outputs: 2}, optimizer.Config{Method: optimizer.Nesterov}, 50, 0, 0},
+		{"adam relu", network.Architecture{Hidden: []int{16, 8}, Outputs: 1, Activations: []network.Activation{network.ReLU, network.Tanh}}, optimizer.Config{Method: optimizer.Adam}, 64, 0.2, 2},
+		{"rmsprop lote completo", network.Architecture{Hidden: []int{4}, Outputs: 1}, optimizer.Config{Method: optimizer.RMSProp}, 0, 0.1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := sequential.NewNeuralNetwork(encoder, scaler, tt.arch, 0.01, testSeed)
+			seq.Epochs, seq.BatchSize, seq.Optimizer = 4, tt.batchSize, tt.opt
+			seq.ValidationFraction, seq.Patience = tt.validation, tt.patience
+			if err := seq.Fit(records); err != nil {
+				t.Fatalf("Fit secuencial: %v", err)
+			}
+
+			for _, workers := range []int{1, 3, 16} {
+				conc := NewNeuralNetwork(encoder, scaler, tt.arch, 0.01, testSeed)
+				conc.Epochs, conc.BatchSize, conc.Optimizer, conc.Workers = 4, tt.batchSize, tt.opt, workers
+				conc.ValidationFraction, conc.Patience = tt.validation, tt.patience
+				if err := conc.Fit(records); err != nil {
+					t.Fatalf("Fit concurrente (%d workers): %v", workers, err)
+				}
+				if !reflect.DeepEqual(conc.Layers, seq.Layers) {
+					t.Errorf("%d workers: las capas no coinciden con las de la versión secuencial", workers)
+				}
+				if conc.BestEpoch != seq.BestEpoch {
+					t.Errorf("%d workers: época conservada %d, se esperaba %d", workers, conc.BestEpoch, seq.BestEpoch)
+				}
+				if got := conc.PredictBatch(records); !slices.Equal(got, seq.PredictBatch(records)) {
+					t.Errorf("%d workers: las predicciones no coinciden con las de la versión secuencial", workers)
+				}
+			}
+		})
+	}
+}
+
+// Arquitectura de las pruebas de rendimiento
+var benchmarkArch = network.Architecture{Hidden: []int{32}, Outputs: 1}
+
+// Escalado del entrenamiento síncrono (una época) con el número de workers, frente a la versión
+// secuencial; todos parten de los mismos pesos y terminan con los mismos. Cada número de workers informa
+// de la aceleración respecto a la secuencial y de la eficiencia (aceleración / workers) si esta se ha medido
+func BenchmarkTrainScaling(b *testing.B) {
+	records, err := preprocess.ReadRecords("../adult.data", 20000)
+	if err != nil {
+		b.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.OneHot)
+	scaler := scaling.Fit(records, encoder.Transform, scaling.ZScore, 1)
+
+	sequentialNs := 0.0 // Tiempo por época de la versión secuencial (0 si no se ha ejecutado)
+	b.Run("secuencial", func(b *testing.B) {
+		for range b.N {
+			nn := sequential.NewNeuralNetwork(encoder, scaler, benchmarkArch, 0.01, testSeed)
+			nn.Epochs = 1
+			nn.Fit(records)
+		}
+		sequentialNs = float64(b.Elapsed().Nanoseconds()) / float64(b.N)
+	})
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for range b.N {
+				nn := NewNeuralNetwork(encoder, scaler, benchmarkArch, 0.01, testSeed)
+				nn.Epochs, nn.Workers = 1, workers
+				nn.Fit(records)
+			}
+			if sequentialNs > 0 {
+				speedup := sequentialNs / (float64(b.Elapsed().Nanoseconds()) / float64(b.N))
+				b.ReportMetric(speedup, "aceleracion")
+				b.ReportMetric(speedup/float64(workers), "eficiencia")
+			}
+		})
+	}
+}
+
+// Entrenamiento síncrono (reducción en árbol) frente a Hogwild! (una época, mismos pesos iniciales y workers)
+func BenchmarkHogwild(b *testing.B) {
+	records, err := preprocess.ReadRecords("../adult.data", 20000)
+	if err != nil {
+		b.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.OneHot)
+	scaler := scaling.Fit(records, encoder.Transform, scaling.ZScore, 1)
+
+	for _, hogwild := range []bool{false, true} {
+		name := "sincrono"
+		if hogwild {
+			name = "hogwild"
+		}
+		for _, workers := range []int{1, 4, 8} {
+			b.Run(fmt.Sprintf("%s/workers=%d", name, workers), func(b *testing.B) {
+				for range b.N {
+					nn := NewNeuralNetwork(encoder, scaler, benchmarkArch, 0.01, testSeed)
+					nn.Epochs, nn.Workers, nn.Hogwild = 1, workers, hogwild
+					nn.Fit(records)
+				}
+			})
+		}
+	}
+}
@@ -1,28 +1,162 @@
 package main
 
 import (
+	"adult/metrics"
+	"adult/persist"
+	"adult/preprocess"
 	"ann/concurrent"
-	"ann/preprocess"
+	"ann/network"
+	"ann/optimizer"
 	"ann/sequential"
+	"flag"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 )
 
+// Semilla para que las ejecuciones sean reproducibles
+const seed = 42
+
+// Convertir una lista de enteros positivos separados por comas ("64,32"), como las neuronas de cada capa oculta
+func parsePositiveInts(list string) ([]int, error) {
+	var values []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		value, err := strconv.Atoi(field)
+		if err != nil || value < 1 {
+			return nil, fmt.Errorf("valor no válido: %q", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Convertir una lista de nombres separados por comas en un valor por capa; un solo nombre se aplica a
+// las n capas y, si hay menos de n, las restantes usan el valor por defecto
+func parseNames[T any](list string, n int, parse func(string) (T, bool)) ([]T, error) {
+	names := strings.Split(list, ",")
+	if len(names) == 1 {
+		names = slices.Repeat(names, n)
+	}
+	if len(names) > n {
+		return nil, fmt.Errorf("hay %d nombres para %d capas", len(names), n)
+	}
+	values := make([]T, len(names))
+	for i, name := range names {
+		value, ok := parse(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("nombre no válido: %q", name)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
 func main() {
+	saveDir := flag.String("save", "", "directorio donde guardar los modelos entrenados (vacío para no guardarlos)")
+	method := flag.String("optimizador", "adam", "método de actualización de los pesos: sgd, nesterov, adam o rmsprop")
+	momentum := flag.Float64("momento", 0.9, "coeficiente de momento de sgd y nesterov (0 para sgd sin momento)")
+	learningRate := flag.Float64("tasa", 0.01, "tasa de aprendizaje del optimizador")
+	batchSize := flag.Int("lote", 64, "registros por mini-lote (0 para usar todos en cada paso)")
+	hidden := flag.String("capas", "10", "neuronas de cada capa oculta separadas por comas (vacío para ninguna)")
+	outputs := flag.Int("salidas", 1, "neuronas de salida: 1 (sigmoide) o una por clase (softmax)")
+	activations := flag.String("activaciones", "sigmoid", "activación de cada capa oculta separadas por comas (sigmoid, relu, leakyrelu, tanh o gelu); una sola se aplica a todas")
+	initializers := flag.String("inicializacion", "auto", "inicialización de cada capa, incluida la de salida, separadas por comas (auto, xavier, he, zeros, uniform o normal); una sola se aplica a todas")
+	epochs := flag.Int("epocas", 50, "número máximo de épocas de entrenamiento")
+	patience := flag.Int("paciencia", 5, "épocas sin mejorar la pérdida de validación antes de parar (0 para no parar antes)")
+	validation := flag.Float64("validacion", 0.1, "fracción de los registros de entrenamiento reservada para la validación y la parada temprana (0 para no reservar)")
+	metricsPath := flag.String("metricas", "", "archivo CSV donde escribir las métricas de cada época (vacío para no escribirlas)")
+	flag.Parse()
+
+	hiddenLayers, err := parsePositiveInts(*hidden)
+	if err != nil {
+		fmt.Printf("Capas ocultas no válidas: %v\n", err)
+		return
+	}
+	if *outputs < 1 {
+		fmt.Printf("Número de neuronas de salida no válido: %d\n", *outputs)
+		return
+	}
+	hiddenActivations, err := parseNames(*activations, len(hiddenLayers), network.ParseActivation)
+	if err != nil {
+		fmt.Printf("Activaciones no válidas: %v\n", err)
+		return
+	}
+	layerInitializers, err := parseNames(*initializers, len(hiddenLayers)+1, network.ParseInitializer)
+	if err != nil {
+		fmt.Printf("Inicializaciones no válidas: %v\n", err)
+		return
+	}
+	arch := network.Architecture{Hidden: hiddenLayers, Outputs: *outputs, Activations: hiddenActivations, Initializers: layerInitializers}
+
+	m, ok := optimizer.ParseMethod(*method)
+	if !ok {
+		fmt.Printf("Optimizador desconocido: %q\n", *method)
+		return
+	}
+	opt := optimizer.Config{Method: m, Momentum: *momentum}
+
 	// Cargar y preprocesar los datos
 	fmt.Println("Cargando y preprocesando datos...")
-	records, err := preprocess.LoadAndPreprocess("adult.data", 1000000) // Cargar 1 millón de registros
+	records, err := preprocess.LoadAndPreprocess("adult.data", 1000000, seed) // Cargar 1 millón de registros
 	if err != nil {
 		fmt.Printf("Error al cargar los datos: %v\n", err)
 		return
 	}
 
-	fmt.Println("Datos cargados correctamente.")
+	// Cargar el conjunto de prueba oficial para que la precisión sea comparable con los resultados publicados
+	testRecords, err := preprocess.LoadTestData("adult.test")
+	if err != nil {
+		fmt.Printf("Error al cargar los datos de prueba: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Datos cargados correctamente (%d de entrenamiento, %d de prueba).\n", len(records), len(testRecords))
+
+	var epochMetrics *metrics.CSVWriter
+	if *metricsPath != "" {
+		epochMetrics, err = metrics.Create(*metricsPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer func() {
+			if err := epochMetrics.Close(); err != nil {
+				fmt.Printf("Error al escribir las métricas: %v\n", err)
+				return
+			}
+			fmt.Printf("Métricas por época guardadas en %s\n", *metricsPath)
+		}()
+	}
+
+	seqConfig := sequential.Config{
+		Architecture:       arch,
+		LearningRate:       *learningRate,
+		BatchSize:          *batchSize,
+		Optimizer:          opt,
+		Epochs:             *epochs,
+		Patience:           *patience,
+		ValidationFraction: *validation,
+		Observer:           epochMetrics.Run("ann_secuencial"),
+	}
+	concConfig := concurrent.Config(seqConfig) // Mismos campos en las dos versiones
+	concConfig.Observer = epochMetrics.Run("ann_concurrente")
 
 	// **Versión secuencial de Redes Neuronales Artificiales**
 	fmt.Println("\n--- Red Neuronal Artificial Secuencial ---")
-	sequential.TestSequentialNN(records)
+	seqModel := sequential.TestSequentialNN(records, testRecords, seed, seqConfig)
+	if seqModel != nil && *saveDir != "" {
+		persist.SaveAll(seqModel.Save, *saveDir, "ann_secuencial")
+	}
 
 	// **Versión concurrente de Redes Neuronales Artificiales**
 	fmt.Println("\n--- Red Neuronal Artificial Concurrente ---")
-	concurrent.TestConcurrentNN(records)
+	concModel := concurrent.TestConcurrentNN(records, testRecords, seed, concConfig)
+	if concModel != nil && *saveDir != "" {
+		persist.SaveAll(concModel.Save, *saveDir, "ann_concurrente")
+	}
 }
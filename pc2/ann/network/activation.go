@@ -0,0 +1,117 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Función de activación de una capa
+type Activation int
+
+const (
+	Sigmoid   Activation = iota // Sigmoide, aplicada a cada neurona por separado
+	Softmax                     // Softmax sobre todas las neuronas de la capa (solo en la capa de salida)
+	ReLU                        // max(0, z)
+	LeakyReLU                   // z si z > 0, leakySlope·z en otro caso
+	Tanh                        // Tangente hiperbólica
+	GELU                        // z·Φ(z), con Φ la función de distribución de la normal estándar
+)
+
+// Pendiente de LeakyReLU para las entradas negativas
+const leakySlope = 0.01
+
+// Nombres de las activaciones, en el orden de las constantes
+var activationNames = []string{"sigmoid", "softmax", "relu", "leakyrelu", "tanh", "gelu"}
+
+// Nombre de la activación (para mostrarla)
+func (a Activation) String() string {
+	if a >= 0 && int(a) < len(activationNames) {
+		return activationNames[a]
+	}
+	return fmt.Sprintf("Activation(%d)", int(a))
+}
+
+// Buscar una activación por su nombre sin distinguir mayúsculas (por ejemplo "relu" o "Tanh")
+func ParseActivation(name string) (Activation, bool) {
+	for i, activationName := range activationNames {
+		if strings.EqualFold(name, activationName) {
+			return Activation(i), true
+		}
+	}
+	return 0, false
+}
+
+// Indica si la activación es de la familia ReLU (se inicializa mejor con He que con Xavier)
+func (a Activation) rectifier() bool {
+	return a == ReLU || a == LeakyReLU || a == GELU
+}
+
+// Aplicar la activación a las entradas netas de una capa, escribiendo el resultado en out
+func (a Activation) apply(z, out []float64) {
+	switch a {
+	case Softmax:
+		maxZ := math.Inf(-1)
+		for _, v := range z {
+			maxZ = max(maxZ, v)
+		}
+		sum := 0.0
+		for i, v := range z {
+			out[i] = math.Exp(v - maxZ) // Restar el máximo evita desbordamientos
+			sum += out[i]
+		}
+		for i := range out {
+			out[i] /= sum
+		}
+	case ReLU:
+		for i, v := range z {
+			out[i] = max(v, 0)
+		}
+	case LeakyReLU:
+		for i, v := range z {
+			if v < 0 {
+				v *= leakySlope
+			}
+			out[i] = v
+		}
+	case Tanh:
+		for i, v := range z {
+			out[i] = math.Tanh(v)
+		}
+	case GELU:
+		for i, v := range z {
+			out[i] = v * normalCDF(v)
+		}
+	default:
+		for i, v := range z {
+			out[i] = 1.0 / (1.0 + math.Exp(-v))
+		}
+	}
+}
+
+// Derivada de la activación respecto a la entrada neta z de una neurona cuya salida es out
+// (solo para activaciones que se aplican neurona a neurona)
+func (a Activation) derivative(z, out float64) float64 {
+	switch a {
+	case ReLU:
+		if z > 0 {
+			return 1
+		}
+		return 0
+	case LeakyReLU:
+		if z > 0 {
+			return 1
+		}
+		return leakySlope
+	case Tanh:
+		return 1 - out*out
+	case GELU:
+		return normalCDF(z) + z*math.Exp(-z*z/2)/math.Sqrt(2*math.Pi)
+	}
+	return out * (1 - out)
+}
+
+// Función de distribución de la normal estándar
+func normalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
@@ -0,0 +1,70 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Método de inicialización de los pesos de una capa (los sesgos empiezan siempre en 0)
+type Initializer int
+
+const (
+	Auto    Initializer = iota // He para la familia ReLU, Xavier para el resto
+	Xavier                     // Xavier/Glorot uniforme: U(-l, l) con l = sqrt(6 / (entradas + salidas))
+	He                         // He normal: N(0, 2 / entradas)
+	Zeros                      // Todos los pesos a 0 (solo útil en la capa de salida: las ocultas quedarían simétricas)
+	Uniform                    // U(-escala, escala)
+	Normal                     // N(0, escala²)
+)
+
+// Escala por defecto de las inicializaciones Uniform y Normal
+const defaultInitScale = 0.05
+
+// Nombres de las inicializaciones, en el orden de las constantes
+var initializerNames = []string{"auto", "xavier", "he", "zeros", "uniform", "normal"}
+
+// Nombre de la inicialización (para mostrarla)
+func (init Initializer) String() string {
+	if init >= 0 && int(init) < len(initializerNames) {
+		return initializerNames[init]
+	}
+	return fmt.Sprintf("Initializer(%d)", int(init))
+}
+
+// Buscar una inicialización por su nombre sin distinguir mayúsculas (por ejemplo "he" o "Xavier")
+func ParseInitializer(name string) (Initializer, bool) {
+	for i, initializerName := range initializerNames {
+		if strings.EqualFold(name, initializerName) {
+			return Initializer(i), true
+		}
+	}
+	return 0, false
+}
+
+// Generar el peso de una conexión entre una capa de inputs entradas y outputs salidas con la activación
+// dada; scale es la escala de Uniform y Normal (defaultInitScale si es 0)
+func (init Initializer) weight(inputs, outputs int, activation Activation, scale float64, rng *rand.Rand) float64 {
+	if init == Auto {
+		init = Xavier
+		if activation.rectifier() {
+			init = He
+		}
+	}
+	if scale == 0 {
+		scale = defaultInitScale
+	}
+	switch init {
+	case Xavier:
+		limit := math.Sqrt(6 / float64(inputs+outputs))
+		return (2*rng.Float64() - 1) * limit
+	case He:
+		return rng.NormFloat64() * math.Sqrt(2/float64(inputs))
+	case Uniform:
+		return (2*rng.Float64() - 1) * scale
+	case Normal:
+		return rng.NormFloat64() * scale
+	}
+	return 0
+}
@@ -0,0 +1,289 @@
+package network
+
+import (
+	"adult/metrics"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// Capa densa: cada neurona combina todas las salidas de la capa anterior
+type Layer struct {
+	Weights    [][]float64 // Weights[j][i]: peso de la entrada i en la neurona j
+	Biases     []float64   // Sesgo de cada neurona
+	Activation Activation
+}
+
+// Crear una capa densa con los pesos generados por el inicializador (scale es la escala de Uniform y
+// Normal, 0 para la de por defecto) y los sesgos a 0
+func NewLayer(inputs, outputs int, activation Activation, init Initializer, scale float64, rng *rand.Rand) *Layer {
+	layer := &Layer{
+		Weights:    make([][]float64, outputs),
+		Biases:     make([]float64, outputs),
+		Activation: activation,
+	}
+	for j := range layer.Weights {
+		layer.Weights[j] = make([]float64, inputs)
+		for i := range layer.Weights[j] {
+			layer.Weights[j][i] = init.weight(inputs, outputs, activation, scale, rng)
+		}
+	}
+	return layer
+}
+
+// Número de entradas de la capa
+func (layer *Layer) Inputs() int {
+	if len(layer.Weights) == 0 {
+		return 0
+	}
+	return len(layer.Weights[0])
+}
+
+// Número de neuronas (salidas) de la capa
+func (layer *Layer) Outputs() int {
+	return len(layer.Weights)
+}
+
+// Arquitectura de un perceptrón multicapa
+type Architecture struct {
+	Hidden       []int         // Neuronas de cada capa oculta, de la entrada a la salida
+	Outputs      int           // Neuronas de salida: 1 con sigmoide (clase positiva) o una por clase con softmax
+	Activations  []Activation  // Activación de cada capa oculta (sigmoide para las que falten)
+	Initializers []Initializer // Inicialización de cada capa, incluida la de salida (Auto para las que falten)
+	InitScale    float64       // Escala de las inicializaciones Uniform y Normal (0 para la de por defecto)
+}
+
+// Activación de la capa oculta l
+func (arch Architecture) activation(l int) Activation {
+	if l < len(arch.Activations) {
+		return arch.Activations[l]
+	}
+	return Sigmoid
+}
+
+// Inicialización de la capa l (la de salida es la última)
+func (arch Architecture) initializer(l int) Initializer {
+	if l < len(arch.Initializers) {
+		return arch.Initializers[l]
+	}
+	return Auto
+}
+
+// Capas de un perceptrón multicapa, de la entrada a la salida
+type Layers []*Layer
+
+// Crear las capas de la arquitectura para el número de entradas dado
+func New(inputs int, arch Architecture, rng *rand.Rand) Layers {
+	layers := make(Layers, 0, len(arch.Hidden)+1)
+	for l, width := range arch.Hidden {
+		layers = append(layers, NewLayer(inputs, width, arch.activation(l), arch.initializer(l), arch.InitScale, rng))
+		inputs = width
+	}
+	output := Sigmoid
+	if arch.Outputs > 1 {
+		output = Softmax
+	}
+	return append(layers, NewLayer(inputs, arch.Outputs, output, arch.initializer(len(arch.Hidden)), arch.InitScale, rng))
+}
+
+// Comprobar que las dimensiones de las capas encajan entre sí, que softmax solo está en la salida y que
+// la salida es sigmoide con una neurona o softmax con varias (la pérdida es la entropía cruzada)
+func (layers Layers) Validate(inputs int) error {
+	if len(layers) == 0 {
+		return fmt.Errorf("la red no tiene capas")
+	}
+	for l, layer := range layers {
+		if layer.Outputs() == 0 || len(layer.Biases) != layer.Outputs() {
+			return fmt.Errorf("la capa %d no tiene neuronas o sus sesgos no coinciden", l)
+		}
+		for _, weights := range layer.Weights {
+			if len(weights) != inputs {
+				return fmt.Errorf("la capa %d espera %d entradas y recibe %d", l, len(weights), inputs)
+			}
+		}
+		if layer.Activation == Softmax && l != len(layers)-1 {
+			return fmt.Errorf("softmax solo puede usarse en la capa de salida (capa %d)", l)
+		}
+		inputs = layer.Outputs()
+	}
+	output, want := layers[len(layers)-1], Sigmoid
+	if output.Outputs() > 1 {
+		want = Softmax
+	}
+	if output.Activation != want {
+		return fmt.Errorf("la capa de salida debe ser sigmoide con una neurona o softmax con varias (%s con %d)", output.Activation, output.Outputs())
+	}
+	return nil
+}
+
+// Forward pass: devuelve las salidas de cada capa precedidas por la entrada (activations[0] = input) y
+// las entradas netas de cada capa (netInputs[l] son las de la capa l), que necesita la retropropagación
+func (layers Layers) Forward(input []float64) (activations, netInputs [][]float64) {
+	activations = make([][]float64, len(layers)+1)
+	netInputs = make([][]float64, len(layers))
+	activations[0] = input
+	for l, layer := range layers {
+		z := make([]float64, layer.Outputs())
+		for j, weights := range layer.Weights {
+			sum := layer.Biases[j]
+			for i, w := range weights {
+				sum += w * input[i]
+			}
+			z[j] = sum
+		}
+		out := make([]float64, len(z))
+		layer.Activation.apply(z, out)
+		netInputs[l], activations[l+1] = z, out
+		input = out
+	}
+	return activations, netInputs
+}
+
+// Salida de la red para una entrada
+func (layers Layers) Output(input []float64) []float64 {
+	activations, _ := layers.Forward(input)
+	return activations[len(activations)-1]
+}
+
+// Entropía cruzada entre la salida de la red y el objetivo: binaria con una neurona de salida (sigmoide)
+// y categórica con varias (softmax)
+func CrossEntropy(output, target []float64) float64 {
+	if len(output) == 1 {
+		return metrics.LogLoss(output[0], target[0])
+	}
+	const eps = 1e-15
+	loss := 0.0
+	for j, t := range target {
+		if t != 0 {
+			loss -= t * math.Log(math.Max(output[j], eps))
+		}
+	}
+	return loss
+}
+
+// Retropropagación de un ejemplo: suma a grads el gradiente de la entropía cruzada entre la salida y el
+// objetivo, y la pérdida a grads.Loss. Con sigmoide (entropía cruzada binaria) o softmax (categórica) en la
+// salida, el error de las neuronas de salida respecto a su entrada neta es simplemente salida - objetivo
+func (layers Layers) Backward(activations, netInputs [][]float64, target []float64, grads *Gradients) {
+	output := activations[len(activations)-1]
+	grads.Loss += CrossEntropy(output, target)
+	delta := make([]float64, len(output))
+	for j := range output {
+		delta[j] = output[j] - target[j]
+	}
+
+	for l := len(layers) - 1; l >= 0; l-- {
+		layer, input := layers[l], activations[l]
+		for j, d := range delta {
+			weightGrads := grads.Weights[l][j]
+			for i, x := range input {
+				weightGrads[i] += d * x
+			}
+			grads.Biases[l][j] += d
+		}
+		if l == 0 {
+			break
+		}
+
+		// Error de la capa anterior: suma de los errores ponderados por los pesos, por la derivada de su activación
+		previous := make([]float64, len(input))
+		for j, d := range delta {
+			for i, w := range layer.Weights[j] {
+				previous[i] += d * w
+			}
+		}
+		for i := range previous {
+			previous[i] *= layers[l-1].Activation.derivative(netInputs[l-1][i], input[i])
+		}
+		delta = previous
+	}
+}
+
+// Copia profunda de las capas
+func (layers Layers) Clone() Layers {
+	clone := make(Layers, len(layers))
+	for l, layer := range layers {
+		weights := make([][]float64, len(layer.Weights))
+		for j := range weights {
+			weights[j] = slices.Clone(layer.Weights[j])
+		}
+		clone[l] = &Layer{Weights: weights, Biases: slices.Clone(layer.Biases), Activation: layer.Activation}
+	}
+	return clone
+}
+
+// Parámetros de todas las capas como grupos para el optimizador (pesos de cada neurona y sesgos de cada
+// capa), en el mismo orden que Gradients.Groups
+func (layers Layers) Parameters() [][]float64 {
+	var groups [][]float64
+	for _, layer := range layers {
+		groups = append(groups, layer.Weights...)
+		groups = append(groups, layer.Biases)
+	}
+	return groups
+}
+
+// Fragmentos (potencia de 2) de cada mini-lote, sumados en árbol igual en las versiones secuencial y concurrente
+const GradientShards = 16
+
+// Gradientes de la pérdida respecto a los pesos y sesgos de cada capa
+type Gradients struct {
+	Weights [][][]float64
+	Biases  [][]float64
+	Loss    float64 // Pérdida total de los ejemplos acumulados (no forma parte de los grupos del optimizador)
+}
+
+// Crear gradientes a cero con las dimensiones de las capas
+func NewGradients(layers Layers) *Gradients {
+	g := &Gradients{
+		Weights: make([][][]float64, len(layers)),
+		Biases:  make([][]float64, len(layers)),
+	}
+	for l, layer := range layers {
+		g.Weights[l] = make([][]float64, layer.Outputs())
+		for j := range g.Weights[l] {
+			g.Weights[l][j] = make([]float64, layer.Inputs())
+		}
+		g.Biases[l] = make([]float64, layer.Outputs())
+	}
+	return g
+}
+
+// Grupos de gradientes para el optimizador, en el mismo orden que Layers.Parameters
+func (g *Gradients) Groups() [][]float64 {
+	var groups [][]float64
+	for l := range g.Weights {
+		groups = append(groups, g.Weights[l]...)
+		groups = append(groups, g.Biases[l])
+	}
+	return groups
+}
+
+// Poner los gradientes y la pérdida a cero
+func (g *Gradients) Reset() {
+	for _, group := range g.Groups() {
+		clear(group)
+	}
+	g.Loss = 0
+}
+
+// Sumar otros gradientes (y su pérdida) a estos
+func (g *Gradients) Add(other *Gradients) {
+	g.Loss += other.Loss
+	otherGroups := other.Groups()
+	for k, group := range g.Groups() {
+		for i, v := range otherGroups[k] {
+			group[i] += v
+		}
+	}
+}
+
+// Multiplicar los gradientes por un factor (1/tamaño del lote para promediarlos); la pérdida no cambia
+func (g *Gradients) Scale(factor float64) {
+	for _, group := range g.Groups() {
+		for i := range group {
+			group[i] *= factor
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Método de actualización de los parámetros
+type Method int
+
+const (
+	SGD      Method = iota // Descenso por gradiente (con Momentum > 0, SGD con momento)
+	Nesterov               // Momento de Nesterov
+	Adam                   // Adam: momentos de primer y segundo orden con corrección de sesgo
+	RMSProp                // RMSProp: paso escalado por la media móvil del gradiente al cuadrado
+)
+
+// Nombre del método (para mostrarlo)
+func (m Method) String() string {
+	switch m {
+	case SGD:
+		return "SGD"
+	case Nesterov:
+		return "Nesterov"
+	case Adam:
+		return "Adam"
+	case RMSProp:
+		return "RMSProp"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// Buscar un método por su nombre sin distinguir mayúsculas (por ejemplo "adam" o "Nesterov")
+func ParseMethod(name string) (Method, bool) {
+	for _, m := range []Method{SGD, Nesterov, Adam, RMSProp} {
+		if strings.EqualFold(name, m.String()) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// Hiperparámetros del optimizador; los campos a 0 toman el valor por defecto indicado
+type Config struct {
+	Method   Method
+	Momentum float64 // Coeficiente de momento de SGD y Nesterov (SGD sin momento si es 0; Nesterov usa 0.9 por defecto)
+	Beta1    float64 // Decaimiento del primer momento de Adam (0.9)
+	Beta2    float64 // Decaimiento del segundo momento de Adam (0.999)
+	Rho      float64 // Decaimiento de la media del gradiente al cuadrado de RMSProp (0.9)
+	Epsilon  float64 // Término de estabilidad numérica de Adam y RMSProp (1e-8)
+}
+
+// Configuración con los valores por defecto aplicados
+func (config Config) withDefaults() Config {
+	if config.Method == Nesterov && config.Momentum == 0 {
+		config.Momentum = 0.9
+	}
+	if config.Beta1 == 0 {
+		config.Beta1 = 0.9
+	}
+	if config.Beta2 == 0 {
+		config.Beta2 = 0.999
+	}
+	if config.Rho == 0 {
+		config.Rho = 0.9
+	}
+	if config.Epsilon == 0 {
+		config.Epsilon = 1e-8
+	}
+	return config
+}
+
+// Optimizador con estado: guarda, para cada grupo de parámetros, la velocidad (SGD con momento y
+// Nesterov) o los momentos (Adam, RMSProp) acumulados entre pasos
+type Optimizer struct {
+	config       Config
+	learningRate float64
+	step         int         // Pasos aplicados (corrección de sesgo de Adam)
+	first        [][]float64 // Velocidad o primer momento de cada grupo
+	second       [][]float64 // Segundo momento de cada grupo (Adam, RMSProp)
+}
+
+// Crear un optimizador con la tasa de aprendizaje dada
+func New(config Config, learningRate float64) *Optimizer {
+	return &Optimizer{config: config.withDefaults(), learningRate: learningRate}
+}
+
+// Aplicar un paso de descenso: params[g] -= actualización(grads[g]) para cada grupo g. Los grupos deben
+// pasarse siempre en el mismo orden y con las mismas longitudes, porque el estado se asocia a su posición
+func (o *Optimizer) Step(params, grads [][]float64) {
+	if o.first == nil {
+		o.first = make([][]float64, len(params))
+		o.second = make([][]float64, len(params))
+		for g := range params {
+			o.first[g] = make([]float64, len(params[g]))
+			if o.config.Method == Adam || o.config.Method == RMSProp {
+				o.second[g] = make([]float64, len(params[g]))
+			}
+		}
+	}
+	o.step++
+
+	c, lr := o.config, o.learningRate
+	// Corrección de sesgo de Adam: los momentos empiezan en 0
+	correction1 := 1 - math.Pow(c.Beta1, float64(o.step))
+	correction2 := 1 - math.Pow(c.Beta2, float64(o.step))
+	for g, param := range params {
+		grad, first, second := grads[g], o.first[g], o.second[g]
+		switch c.Method {
+		case SGD:
+			for i, gi := range grad {
+				if c.Momentum == 0 {
+					param[i] -= lr * gi
+					continue
+				}
+				first[i] = c.Momentum*first[i] + gi
+				param[i] -= lr * first[i]
+			}
+		case Nesterov:
+			for i, gi := range grad {
+				first[i] = c.Momentum*first[i] + gi
+				param[i] -= lr * (gi + c.Momentum*first[i]) // Gradiente evaluado "por delante" de la velocidad
+			}
+		case Adam:
+			for i, gi := range grad {
+				first[i] = c.Beta1*first[i] + (1-c.Beta1)*gi
+				second[i] = c.Beta2*second[i] + (1-c.Beta2)*gi*gi
+				param[i] -= lr * (first[i] / correction1) / (math.Sqrt(second[i]/correction2) + c.Epsilon)
+			}
+		case RMSProp:
+			for i, gi := range grad {
+				second[i] = c.Rho*second[i] + (1-c.Rho)*gi*gi
+				param[i] -= lr * gi / (math.Sqrt(second[i]) + c.Epsilon)
+			}
+		}
+	}
+}
@@ -1,191 +1,305 @@
 package sequential
 
 import (
-	"ann/preprocess"
+	"adult/classifier"
+	"adult/encoding"
+	"adult/metrics"
+	"adult/persist"
+	"adult/preprocess"
+	"adult/random"
+	"adult/scaling"
+	"ann/network"
+	"ann/optimizer"
 	"fmt"
-	"math"
-	"math/rand"
 	"time"
 )
 
+// La red implementa la interfaz común de clasificadores
+var _ classifier.Classifier = (*NeuralNetwork)(nil)
+
+// Valores por defecto de Fit
+const (
+	defaultEpochs    = 50
+	defaultBatchSize = 64
+	defaultPatience  = 5
+)
+
 // Estructura de la red neuronal
 type NeuralNetwork struct {
-	InputNeurons  int
-	HiddenNeurons int
-	OutputNeurons int
-	LearningRate  float64
-	WeightsIH     [][]float64 // Pesos entre la capa de entrada y la oculta
-	WeightsHO     []float64   // Pesos entre la capa oculta y la de salida
-	BiasH         []float64   // Sesgo para las neuronas ocultas
-	BiasO         float64     // Sesgo para la neurona de salida
-}
-
-// Función para crear y inicializar una red neuronal
-func NewNeuralNetwork(inputNeurons, hiddenNeurons, outputNeurons int, learningRate float64) *NeuralNetwork {
-	rand.Seed(time.Now().UnixNano())
+	InputNeurons       int
+	Architecture       network.Architecture // Capas ocultas y neuronas de salida
+	LearningRate       float64
+	Epochs             int               // Número de épocas que usa Fit
+	BatchSize          int               // Registros por mini-lote (<= 0 para usar todos en cada paso)
+	Optimizer          optimizer.Config  // Método de actualización de los pesos y sus hiperparámetros
+	Seed               int64             // Semilla del barajado de los registros en cada época y de la validación
+	ValidationFraction float64           // Fracción de registros que Fit reserva para la validación y la parada temprana (0 para no reservar)
+	Patience           int               // Épocas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	BestEpoch          int               // Época cuyos pesos se conservaron (la de menor pérdida de validación; 0 sin validación)
+	Layers             network.Layers    // Capas densas, de la entrada a la salida
+	Encoder            *encoding.Encoder // Codificador de características ajustado con los datos de entrenamiento
+	Scaler             *scaling.Scaler   // Escalador ajustado con los datos de entrenamiento (nil para no escalar)
+
+	observers []metrics.Observer // Reciben las métricas de cada época (no se guardan con el modelo)
+}
+
+// Función para crear y inicializar una red neuronal con la arquitectura dada (una neurona de entrada por cada característica del codificador)
+func NewNeuralNetwork(encoder *encoding.Encoder, scaler *scaling.Scaler, arch network.Architecture, learningRate float64, seed int64) *NeuralNetwork {
+	rng := random.New(seed)
+	inputNeurons := encoder.NumFeatures()
 	nn := &NeuralNetwork{
-		InputNeurons:  inputNeurons,
-		HiddenNeurons: hiddenNeurons,
-		OutputNeurons: outputNeurons,
-		LearningRate:  learningRate,
-		WeightsIH:     make([][]float64, hiddenNeurons),
-		WeightsHO:     make([]float64, hiddenNeurons),
-		BiasH:         make([]float64, hiddenNeurons),
-		BiasO:         rand.Float64(),
-	}
-
-	// Inicializar los pesos aleatoriamente
-	for i := range nn.WeightsIH {
-		nn.WeightsIH[i] = make([]float64, inputNeurons)
-		for j := range nn.WeightsIH[i] {
-			nn.WeightsIH[i][j] = rand.Float64() * 0.1
-		}
-		nn.WeightsHO[i] = rand.Float64() * 0.1
-		nn.BiasH[i] = rand.Float64() * 0.1
+		InputNeurons: inputNeurons,
+		Architecture: arch,
+		LearningRate: learningRate,
+		Epochs:       defaultEpochs,
+		BatchSize:    defaultBatchSize,
+		Patience:     defaultPatience,
+		Optimizer:    optimizer.Config{Method: optimizer.Adam},
+		Seed:         seed,
+		Layers:       network.New(inputNeurons, arch, rng),
+		Encoder:      encoder,
+		Scaler:       scaler,
 	}
-
 	return nn
 }
 
-// Función de activación (sigmoide)
-func sigmoid(x float64) float64 {
-	return 1.0 / (1.0 + math.Exp(-x))
+// Añadir un observador que recibe las métricas de cada época del entrenamiento
+func (nn *NeuralNetwork) AddObserver(observer metrics.Observer) {
+	nn.observers = append(nn.observers, observer)
 }
 
-// Derivada de la función sigmoide
-func sigmoidDerivative(x float64) float64 {
-	return x * (1 - x)
+// Forward pass y retropropagación de un registro: suma a g el gradiente de su pérdida
+func (nn *NeuralNetwork) backward(record preprocess.Record, g *network.Gradients) {
+	activations, netInputs := nn.Layers.Forward(nn.extractFeatures(record))
+	nn.Layers.Backward(activations, netInputs, nn.target(record), g)
 }
 
-// Función para entrenar la red neuronal (gradiente descendente)
-func (nn *NeuralNetwork) Train(record preprocess.Record, target float64) {
-	// Fase de forward pass
-	features := extractFeatures(record)
-	hiddenInputs := make([]float64, nn.HiddenNeurons)
-	hiddenOutputs := make([]float64, nn.HiddenNeurons)
-
-	// Cálculo de la capa oculta
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		sum := nn.BiasH[i]
-		for j := 0; j < nn.InputNeurons; j++ {
-			sum += nn.WeightsIH[i][j] * features[j]
-		}
-		hiddenInputs[i] = sum
-		hiddenOutputs[i] = sigmoid(sum)
+// Salida esperada para un registro: la etiqueta 0/1 con una neurona de salida, o la clase en one-hot
+// (0 = NegativeLabel, 1 = PositiveLabel) con una neurona por clase
+func (nn *NeuralNetwork) target(record preprocess.Record) []float64 {
+	label := convertLabel(record.Income)
+	outputs := nn.Layers[len(nn.Layers)-1].Outputs()
+	if outputs == 1 {
+		return []float64{label}
 	}
+	target := make([]float64, outputs)
+	target[int(label)] = 1
+	return target
+}
 
-	// Cálculo de la salida final
-	finalInput := nn.BiasO
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		finalInput += nn.WeightsHO[i] * hiddenOutputs[i]
+// Aplicar un paso del optimizador con los gradientes promediados del mini-lote
+func (nn *NeuralNetwork) step(opt *optimizer.Optimizer, g *network.Gradients) {
+	opt.Step(nn.Layers.Parameters(), g.Groups())
+}
+
+// Tamaño de mini-lote efectivo (todo el conjunto si BatchSize <= 0)
+func (nn *NeuralNetwork) batchSize(numRecords int) int {
+	if nn.BatchSize <= 0 || nn.BatchSize > numRecords {
+		return numRecords
 	}
-	finalOutput := sigmoid(finalInput)
+	return nn.BatchSize
+}
 
-	// Fase de retropropagación del error (backpropagation)
-	outputError := target - finalOutput
-	gradient := outputError * sigmoidDerivative(finalOutput)
+// Función para entrenar la red neuronal por mini-lotes: en cada época se barajan los registros con un
+// generador derivado de la semilla y, por cada lote, se aplica el optimizador al gradiente medio
+func (nn *NeuralNetwork) Train(records []preprocess.Record, epochs int) {
+	nn.train(records, nil, epochs)
+}
 
-	// Actualizar los pesos de la capa de salida
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		delta := gradient * hiddenOutputs[i]
-		nn.WeightsHO[i] += nn.LearningRate * delta
+// Entrenar con los registros e informar a los observadores al final de cada época de la pérdida media de
+// entrenamiento y, si hay registros de validación, de la pérdida y la precisión sobre ellos. Con validación,
+// el entrenamiento para tras nn.Patience épocas sin mejorar la pérdida y conserva los mejores pesos
+func (nn *NeuralNetwork) train(records, validation []preprocess.Record, epochs int) {
+	if len(records) == 0 {
+		return
+	}
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(nn.Patience)
+	opt := optimizer.New(nn.Optimizer, nn.LearningRate)
+	batchSize := nn.batchSize(len(records))
+	shards := make([]*network.Gradients, network.GradientShards)
+	for k := range shards {
+		shards[k] = network.NewGradients(nn.Layers)
 	}
-	nn.BiasO += nn.LearningRate * gradient
 
-	// Calcular el error de la capa oculta y actualizar los pesos de la capa oculta
-	hiddenErrors := make([]float64, nn.HiddenNeurons)
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		hiddenErrors[i] = gradient * nn.WeightsHO[i]
-		hiddenGradient := hiddenErrors[i] * sigmoidDerivative(hiddenOutputs[i])
+	for epoch := 0; epoch < epochs; epoch++ {
+		order := random.Derive(nn.Seed, epoch).Perm(len(records))
+		loss := 0.0
+		for first := 0; first < len(order); first += batchSize {
+			batch := order[first:min(first+batchSize, len(order))]
+			for k, shard := range shards {
+				shard.Reset()
+				for _, index := range batch[k*len(batch)/network.GradientShards : (k+1)*len(batch)/network.GradientShards] {
+					nn.backward(records[index], shard)
+				}
+			}
 
-		for j := 0; j < nn.InputNeurons; j++ {
-			nn.WeightsIH[i][j] += nn.LearningRate * hiddenGradient * features[j]
+			// Reducción en árbol: en cada nivel, el primer fragmento de cada pareja suma el segundo; el total queda en el fragmento 0
+			for span := 1; span < network.GradientShards; span *= 2 {
+				for k := 0; k < network.GradientShards; k += 2 * span {
+					shards[k].Add(shards[k+span])
+				}
+			}
+			loss += shards[0].Loss
+			shards[0].Scale(1 / float64(len(batch)))
+			nn.step(opt, shards[0])
+		}
+		m := metrics.Report(nn.observers, epoch+1, loss/float64(len(records)), start, validation, nn.Evaluate)
+		if len(validation) > 0 && stopping.Check(m, nn.snapshot) {
+			break
 		}
-		nn.BiasH[i] += nn.LearningRate * hiddenGradient
 	}
+	nn.BestEpoch = stopping.Restore()
 }
 
-// Función para predecir usando la red neuronal
-func (nn *NeuralNetwork) Predict(record preprocess.Record) float64 {
-	features := extractFeatures(record)
-	hiddenOutputs := make([]float64, nn.HiddenNeurons)
+// Función para copiar las capas y devolver la función que las restaura (parada temprana)
+func (nn *NeuralNetwork) snapshot() func() {
+	layers := nn.Layers.Clone()
+	return func() { nn.Layers = layers }
+}
 
-	// Cálculo de la capa oculta
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		sum := nn.BiasH[i]
-		for j := 0; j < nn.InputNeurons; j++ {
-			sum += nn.WeightsIH[i][j] * features[j]
+// Función para calcular la entropía cruzada media y la precisión de la red sobre los registros. Las sumas
+// se hacen por los mismos fragmentos que la versión concurrente para obtener exactamente el mismo resultado
+func (nn *NeuralNetwork) Evaluate(records []preprocess.Record) (loss, accuracy float64) {
+	correct := 0
+	for k := 0; k < network.GradientShards; k++ {
+		shardLoss := 0.0
+		for _, record := range records[k*len(records)/network.GradientShards : (k+1)*len(records)/network.GradientShards] {
+			outputs := nn.PredictOutputs(record)
+			shardLoss += network.CrossEntropy(outputs, nn.target(record))
+			if classifier.LabelFromProba(positiveProba(outputs)) == record.Income {
+				correct++
+			}
 		}
-		hiddenOutputs[i] = sigmoid(sum)
+		loss += shardLoss
 	}
+	return loss / float64(len(records)), float64(correct) / float64(len(records))
+}
+
+// Función para calcular las salidas de la red para un registro (con softmax, la probabilidad de cada clase)
+func (nn *NeuralNetwork) PredictOutputs(record preprocess.Record) []float64 {
+	return nn.Layers.Output(nn.extractFeatures(record))
+}
 
-	// Cálculo de la salida final
-	finalInput := nn.BiasO
-	for i := 0; i < nn.HiddenNeurons; i++ {
-		finalInput += nn.WeightsHO[i] * hiddenOutputs[i]
+// Función para estimar la probabilidad de la clase positiva (salida sigmoide, o salida softmax de la clase positiva)
+func (nn *NeuralNetwork) PredictProba(record preprocess.Record) float64 {
+	return positiveProba(nn.PredictOutputs(record))
+}
+
+// Probabilidad de la clase positiva a partir de las salidas de la red
+func positiveProba(outputs []float64) float64 {
+	if len(outputs) == 1 {
+		return outputs[0]
 	}
-	finalOutput := sigmoid(finalInput)
+	return outputs[1]
+}
 
-	return finalOutput
+// Función para entrenar la red con los registros durante nn.Epochs épocas, reservando nn.ValidationFraction
+// de ellos para las métricas de validación (implementa classifier.Classifier)
+func (nn *NeuralNetwork) Fit(records []preprocess.Record) error {
+	if len(records) == 0 {
+		return classifier.ErrNoRecords
+	}
+	if err := nn.Layers.Validate(nn.InputNeurons); err != nil {
+		return err
+	}
+	train, validation := preprocess.SplitValidation(records, nn.ValidationFraction, nn.Seed)
+	nn.train(train, validation, nn.Epochs)
+	return nil
+}
+
+// Función para predecir la etiqueta del registro
+func (nn *NeuralNetwork) Predict(record preprocess.Record) string {
+	return classifier.LabelFromProba(nn.PredictProba(record))
 }
 
-// Función para extraer características numéricas del registro
-func extractFeatures(record preprocess.Record) []float64 {
-	return []float64{
-		float64(record.Age),
-		float64(record.Fnlwgt),
-		float64(record.EducationNum),
-		float64(record.CapitalGain),
-		float64(record.CapitalLoss),
-		float64(record.HoursPerWeek),
+// Función para predecir las etiquetas de varios registros
+func (nn *NeuralNetwork) PredictBatch(records []preprocess.Record) []string {
+	return classifier.BatchPredict(nn.Predict, records, 1)
+}
+
+// Función para extraer las características (numéricas y categóricas codificadas) del registro
+func (nn *NeuralNetwork) extractFeatures(record preprocess.Record) []float64 {
+	features := nn.Encoder.Transform(record)
+	if nn.Scaler != nil {
+		features = nn.Scaler.Transform(features)
 	}
+	return features
 }
 
 // Función para convertir la etiqueta de ingreso a 0 o 1
 func convertLabel(income string) float64 {
-	if income == ">50K" {
+	if income == preprocess.PositiveLabel {
 		return 1.0
 	}
 	return 0.0
 }
 
-// Función para probar la red neuronal secuencial
-func TestSequentialNN(records []preprocess.Record) {
-	// Dividir datos en entrenamiento y prueba (80% entrenamiento, 20% prueba)
-	numTrain := int(0.8 * float64(len(records)))
-	trainData := records[:numTrain]
-	testData := records[numTrain:]
+// Tipo de modelo en los archivos guardados (el mismo que en la versión concurrente, son intercambiables)
+const modelKind = "ann"
+
+// Versión del formato de la red en disco (2: lista de capas en lugar de una sola capa oculta)
+const modelVersion = 2
+
+// Guardar la red (pesos, codificador y escalador) en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func (nn *NeuralNetwork) Save(path string) error {
+	return persist.Save(path, modelKind, modelVersion, nn)
+}
+
+// Cargar un modelo guardado con Save
+func Load(path string) (*NeuralNetwork, error) {
+	nn := &NeuralNetwork{}
+	if err := persist.Load(path, modelKind, modelVersion, nn); err != nil {
+		return nil, err
+	}
+	if err := nn.Layers.Validate(nn.InputNeurons); err != nil {
+		return nil, fmt.Errorf("modelo no válido: %v", err)
+	}
+	return nn, nil
+}
+
+// Configuración del entrenamiento de TestSequentialNN
+type Config struct {
+	Architecture       network.Architecture // Capas ocultas y neuronas de salida
+	LearningRate       float64              // Tasa de aprendizaje del optimizador
+	BatchSize          int                  // Registros por mini-lote (<= 0 para usar todos en cada paso)
+	Optimizer          optimizer.Config     // Método de actualización de los pesos y sus hiperparámetros
+	Epochs             int                  // Número máximo de épocas
+	Patience           int                  // Épocas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	ValidationFraction float64              // Fracción de los registros reservada para la validación (0 para no reservar)
+	Observer           metrics.Observer     // Recibe las métricas de cada época (nil para no informar)
+}
 
+// Función para probar la red neuronal secuencial (devuelve el modelo entrenado)
+func TestSequentialNN(trainData, testData []preprocess.Record, seed int64, config Config) *NeuralNetwork {
 	// Crear y entrenar la red neuronal
 	fmt.Println("Entrenando Red Neuronal Secuencial...")
-	nn := NewNeuralNetwork(6, 10, 1, 0.01) // 6 neuronas de entrada, 10 ocultas, 1 de salida, tasa de aprendizaje 0.01
+	encoder := encoding.Fit(trainData, encoding.OneHot)
+	scaler := scaling.Fit(trainData, encoder.Transform, scaling.ZScore, 1)
+	nn := NewNeuralNetwork(encoder, scaler, config.Architecture, config.LearningRate, seed) // Entradas según el codificador
+	nn.BatchSize, nn.Optimizer = config.BatchSize, config.Optimizer
+	nn.Epochs, nn.Patience, nn.ValidationFraction = config.Epochs, config.Patience, config.ValidationFraction
+	if config.Observer != nil {
+		nn.AddObserver(config.Observer)
+	}
+	fmt.Printf("Capas ocultas: %v (activaciones %v), neuronas de salida: %d, inicialización %v\n", config.Architecture.Hidden, config.Architecture.Activations, config.Architecture.Outputs, config.Architecture.Initializers)
+	fmt.Printf("Optimizador: %s, tasa de aprendizaje %g, mini-lotes de %d registros\n", config.Optimizer.Method, config.LearningRate, config.BatchSize)
 
 	start := time.Now()
-	epochs := 50 // Número de épocas
-	for epoch := 0; epoch < epochs; epoch++ {
-		for _, record := range trainData {
-			label := convertLabel(record.Income)
-			nn.Train(record, label)
-		}
+	if err := nn.Fit(trainData); err != nil {
+		fmt.Printf("Error al entrenar: %v\n", err)
+		return nil
 	}
 	elapsed := time.Since(start)
 	fmt.Printf("Tiempo de entrenamiento: %s\n", elapsed)
+	if nn.BestEpoch > 0 {
+		fmt.Printf("Parada temprana: pesos de la época %d (de %d como máximo)\n", nn.BestEpoch, config.Epochs)
+	}
 
 	// Probar el modelo
 	fmt.Println("Probando Red Neuronal Secuencial...")
-	correct := 0
-	for _, record := range testData {
-		output := nn.Predict(record)
-		prediction := 0.0
-		if output > 0.5 {
-			prediction = 1.0
-		}
-		label := convertLabel(record.Income)
-		if prediction == label {
-			correct++
-		}
-	}
-
-	accuracy := float64(correct) / float64(len(testData))
+	accuracy := classifier.Accuracy(nn, testData)
 	fmt.Printf("Precisión: %.2f%%\n", accuracy*100)
+
+	return nn
 }
@@ -1,16 +1,20 @@
 package concurrent
 
 import (
+	"adult/atomicfloat"
+	"adult/metrics"
+	"adult/persist"
 	"filtrado/preprocess"
+	"fmt"
 	"math"
 	"math/rand"
+	"slices"
 	"sync"
 	"time"
 )
 
 // Factores Latentes
-const K = 10 // Número de factores latentes
-const epochs = 50
+const K = 10        // Número de factores latentes
 const alpha = 0.01  // Tasa de aprendizaje
 const lambda = 0.02 // Regularización
 
@@ -18,35 +22,44 @@ const lambda = 0.02 // Regularización
 var P [][]float64
 var Q [][]float64
 
-// Inicializa las matrices P y Q
-func InitializeMatrices(numUsers, numMovies int) {
-	rand.Seed(time.Now().UnixNano())
+// Configuración del entrenamiento
+type Config struct {
+	Epochs     int                 // Número máximo de épocas
+	Patience   int                 // Épocas sin mejorar el RMSE de validación antes de parar (<= 0 para no parar antes)
+	Observer   metrics.Observer    // Recibe el RMSE de entrenamiento y de validación de cada época (nil para no informar)
+	Validation []preprocess.Rating // Calificaciones de la parada temprana (vacío para entrenar todas las épocas)
+}
+
+// Inicializa las matrices P y Q con un generador propio (la semilla hace reproducible el entrenamiento)
+func InitializeMatrices(numUsers, numMovies int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
 	P = make([][]float64, numUsers+1)
 	Q = make([][]float64, numMovies+1)
 
 	for i := range P {
 		P[i] = make([]float64, K)
 		for k := range P[i] {
-			P[i][k] = rand.Float64()
+			P[i][k] = rng.Float64()
 		}
 	}
 
 	for i := range Q {
 		Q[i] = make([]float64, K)
 		for k := range Q[i] {
-			Q[i][k] = rand.Float64()
+			Q[i][k] = rng.Float64()
 		}
 	}
 }
 
-// Función concurrente para actualizar P y Q
-func update(user, movie int, rating float64, wg *sync.WaitGroup, mu *sync.Mutex) {
+// Función concurrente para actualizar P y Q (suma a squaredErr el error al cuadrado de la calificación)
+func update(user, movie int, rating float64, wg *sync.WaitGroup, mu *sync.Mutex, squaredErr *float64) {
 	defer wg.Done()
 
+	mu.Lock() // Adquiere el mutex para asegurar que solo una goroutine lea y modifique las matrices a la vez
 	pred := PredictRating(user, movie)
 	err := rating - pred
+	*squaredErr += err * err
 
-	mu.Lock() // Adquiere el mutex para asegurar que solo una goroutine modifique las matrices a la vez
 	// Actualización de P y Q
 	for k := 0; k < K; k++ {
 		P[user][k] += alpha * (err*Q[movie][k] - lambda*P[user][k])
@@ -64,19 +77,80 @@ func PredictRating(user, movie int) float64 {
 	return pred
 }
 
-// Entrenamiento concurrente
-func TrainConcurrent(ratings []preprocess.Rating, numUsers, numMovies int) {
-	InitializeMatrices(numUsers, numMovies)
+// Entrenamiento concurrente; devuelve la época cuyos factores se conservaron (0 sin validación)
+func TrainConcurrent(ratings []preprocess.Rating, numUsers, numMovies int, seed int64, config Config) int {
+	InitializeMatrices(numUsers, numMovies, seed)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(config.Patience)
 
-	for epoch := 0; epoch < epochs; epoch++ {
+	for epoch := 0; epoch < config.Epochs; epoch++ {
+		var squaredErr float64 // Error de cada calificación antes de su actualización (protegido por el mutex)
 		for _, r := range ratings {
 			wg.Add(1)
-			go update(r.UserID, r.MovieID, r.Rating, &wg, &mu)
+			go update(r.UserID, r.MovieID, r.Rating, &wg, &mu, &squaredErr)
 		}
 		wg.Wait() // Esperar a que todas las goroutines terminen
+		m := metrics.Report([]metrics.Observer{config.Observer}, epoch+1, math.Sqrt(squaredErr/float64(len(ratings))), start, config.Validation, validationRMSE)
+		if len(config.Validation) > 0 && stopping.Check(m, snapshot) {
+			break
+		}
 	}
+	return stopping.Restore()
+}
+
+// Entrenamiento Hogwild!: en cada época cada worker recorre un bloque contiguo de calificaciones y actualiza
+// P y Q sin mutex, leyendo y sumando cada factor de forma atómica. Dos workers que comparten usuario o película
+// pueden pisarse los cálculos (no las escrituras); con matrices grandes y dispersas esas colisiones son raras.
+// Devuelve la época cuyos factores se conservaron (0 sin validación)
+func TrainHogwild(ratings []preprocess.Rating, numUsers, numMovies int, seed int64, workers int, config Config) int {
+	InitializeMatrices(numUsers, numMovies, seed)
+	workers = max(1, workers)
+	chunkSize := (len(ratings) + workers - 1) / workers
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(config.Patience)
+
+	for epoch := 0; epoch < config.Epochs; epoch++ {
+		var wg sync.WaitGroup
+		chunkErr := make([]float64, workers) // Error al cuadrado de las calificaciones de cada bloque
+		for c, first := 0, 0; first < len(ratings); c, first = c+1, first+chunkSize {
+			wg.Add(1)
+			go func(c int, chunk []preprocess.Rating) {
+				defer wg.Done()
+				p, q := make([]float64, K), make([]float64, K) // Instantáneas de los factores de la calificación
+				for _, r := range chunk {
+					user, movie := r.UserID, r.MovieID
+					atomicfloat.LoadSlice(p, P[user])
+					atomicfloat.LoadSlice(q, Q[movie])
+					var pred float64
+					for k := 0; k < K; k++ {
+						pred += p[k] * q[k]
+					}
+					err := r.Rating - pred
+					chunkErr[c] += err * err
+
+					// Misma actualización que la versión con mutex (Q usa el factor de P ya actualizado)
+					for k := 0; k < K; k++ {
+						deltaP := alpha * (err*q[k] - lambda*p[k])
+						atomicfloat.Add(&P[user][k], deltaP)
+						atomicfloat.Add(&Q[movie][k], alpha*(err*(p[k]+deltaP)-lambda*q[k]))
+					}
+				}
+			}(c, ratings[first:min(first+chunkSize, len(ratings))])
+		}
+		wg.Wait()
+
+		var squaredErr float64
+		for _, e := range chunkErr {
+			squaredErr += e
+		}
+		m := metrics.Report([]metrics.Observer{config.Observer}, epoch+1, math.Sqrt(squaredErr/float64(len(ratings))), start, config.Validation, validationRMSE)
+		if len(config.Validation) > 0 && stopping.Check(m, snapshot) {
+			break
+		}
+	}
+	return stopping.Restore()
 }
 
 // Evalúa el modelo concurrente usando el conjunto de prueba
@@ -89,3 +163,54 @@ func EvaluateConcurrent(testSet []preprocess.Rating) float64 {
 	}
 	return mse / float64(len(testSet))
 }
+
+// Función para calcular el RMSE de validación (la factorización no clasifica, así que no hay precisión)
+func validationRMSE(ratings []preprocess.Rating) (float64, float64) {
+	return math.Sqrt(EvaluateConcurrent(ratings)), math.NaN()
+}
+
+// Función para copiar P y Q y devolver la función que los restaura (parada temprana)
+func snapshot() func() {
+	p, q := cloneMatrix(P), cloneMatrix(Q)
+	return func() { P, Q = p, q }
+}
+
+// Copia profunda de una matriz de factores
+func cloneMatrix(m [][]float64) [][]float64 {
+	clone := make([][]float64, len(m))
+	for i := range m {
+		clone[i] = slices.Clone(m[i])
+	}
+	return clone
+}
+
+// Tipo de modelo en los archivos guardados (el mismo en la versión secuencial y la concurrente)
+const modelKind = "matrix-factorization"
+
+// Versión del formato de la factorización en disco
+const modelVersion = 1
+
+// Factores latentes tal como se guardan en disco
+type savedModel struct {
+	K int
+	P [][]float64
+	Q [][]float64
+}
+
+// Guarda las matrices P y Q en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func SaveModel(path string) error {
+	return persist.Save(path, modelKind, modelVersion, savedModel{K: K, P: P, Q: Q})
+}
+
+// Carga las matrices P y Q guardadas con SaveModel
+func LoadModel(path string) error {
+	var model savedModel
+	if err := persist.Load(path, modelKind, modelVersion, &model); err != nil {
+		return err
+	}
+	if model.K != K {
+		return fmt.Errorf("el modelo tiene %d factores latentes, se esperaban %d", model.K, K)
+	}
+	P, Q = model.P, model.Q
+	return nil
+}
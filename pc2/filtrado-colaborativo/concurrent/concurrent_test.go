@@ -0,0 +1,108 @@
+package concurrent
+
+import (
+	"filtrado/preprocess"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Semilla fija de las pruebas
+const testSeed = 42
+
+// Dimensiones de las calificaciones sintéticas de las pruebas
+const (
+	testUsers   = 500
+	testMovies  = 300
+	testRatings = 30000
+)
+
+// Generar calificaciones de 1 a 5 con estructura de bajo rango: cada usuario tiene un nivel de exigencia y
+// cada película una calidad y un género, y la nota depende de ellos y de la afinidad del usuario con el género
+func syntheticRatings(seed int64) []preprocess.Rating {
+	rng := rand.New(rand.NewSource(seed))
+	userBias, affinity := make([]float64, testUsers+1), make([]float64, testUsers+1)
+	for u := range userBias {
+		userBias[u], affinity[u] = rng.NormFloat64()*0.5, rng.NormFloat64()
+	}
+	quality, genre := make([]float64, testMovies+1), make([]float64, testMovies+1)
+	for m := range quality {
+		quality[m], genre[m] = rng.NormFloat64()*0.5, rng.NormFloat64()
+	}
+
+	ratings := make([]preprocess.Rating, testRatings)
+	for i := range ratings {
+		user, movie := 1+rng.Intn(testUsers), 1+rng.Intn(testMovies)
+		rating := 3 + userBias[user] + quality[movie] + 0.5*affinity[user]*genre[movie] + rng.NormFloat64()*0.3
+		ratings[i] = preprocess.Rating{UserID: user, MovieID: movie, Rating: math.Max(1, math.Min(5, math.Round(rating)))}
+	}
+	return ratings
+}
+
+// RMSE de predecir siempre la media de las calificaciones de entrenamiento
+func baselineRMSE(train, test []preprocess.Rating) float64 {
+	mean := 0.0
+	for _, r := range train {
+		mean += r.Rating
+	}
+	mean /= float64(len(train))
+	mse := 0.0
+	for _, r := range test {
+		mse += (r.Rating - mean) * (r.Rating - mean)
+	}
+	return math.Sqrt(mse / float64(len(test)))
+}
+
+// El entrenamiento con mutex y el Hogwild! deben dejar un RMSE de prueba finito y mejor que el de predecir
+// la media, y con validación deben conservar una época dentro del máximo
+func TestTrainRMSE(t *testing.T) {
+	train, test := preprocess.SplitData(syntheticRatings(testSeed), 0.8)
+	train, validation := preprocess.SplitData(train, 0.9)
+	baseline := baselineRMSE(train, test)
+
+	trainers := []struct {
+		name  string
+		train func(config Config) int
+	}{
+		{"mutex", func(config Config) int { return TrainConcurrent(train, testUsers, testMovies, testSeed, config) }},
+		{"hogwild/workers=1", func(config Config) int { return TrainHogwild(train, testUsers, testMovies, testSeed, 1, config) }},
+		{"hogwild/workers=4", func(config Config) int { return TrainHogwild(train, testUsers, testMovies, testSeed, 4, config) }},
+	}
+	for _, trainer := range trainers {
+		t.Run(trainer.name, func(t *testing.T) {
+			bestEpoch := trainer.train(Config{Epochs: 20})
+			if bestEpoch != 0 {
+				t.Errorf("época conservada %d sin validación, se esperaba 0", bestEpoch)
+			}
+			if rmse := math.Sqrt(EvaluateConcurrent(test)); math.IsNaN(rmse) || rmse >= baseline {
+				t.Errorf("RMSE de prueba %.4f, se esperaba menor que el de predecir la media (%.4f)", rmse, baseline)
+			}
+
+			bestEpoch = trainer.train(Config{Epochs: 20, Patience: 2, Validation: validation})
+			if bestEpoch < 1 || bestEpoch > 20 {
+				t.Errorf("época conservada %d, se esperaba entre 1 y 20", bestEpoch)
+			}
+		})
+	}
+}
+
+// Una época con mutex (una goroutine por calificación) con las calificaciones sintéticas
+func BenchmarkTrainConcurrent(b *testing.B) {
+	ratings := syntheticRatings(testSeed)
+	for range b.N {
+		TrainConcurrent(ratings, testUsers, testMovies, testSeed, Config{Epochs: 1})
+	}
+}
+
+// Una época Hogwild! con las mismas calificaciones y varios números de workers
+func BenchmarkHogwild(b *testing.B) {
+	ratings := syntheticRatings(testSeed)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for range b.N {
+				TrainHogwild(ratings, testUsers, testMovies, testSeed, workers, Config{Epochs: 1})
+			}
+		})
+	}
+}
@@ -1,14 +1,37 @@
 package main
 
 import (
+	"adult/metrics"
+	"adult/persist"
 	"filtrado/concurrent"
 	"filtrado/preprocess"
 	"filtrado/sequential"
+	"flag"
 	"fmt"
 	"time"
 )
 
+// Semilla para que las ejecuciones sean reproducibles
+const seed = 42
+
+// Workers del entrenamiento Hogwild!
+const hogwildWorkers = 4
+
+// Mostrar la época cuyos factores conservó la parada temprana (nada si no hubo validación)
+func printBestEpoch(bestEpoch, epochs int) {
+	if bestEpoch > 0 {
+		fmt.Printf("Parada temprana: factores de la época %d (de %d como máximo)\n", bestEpoch, epochs)
+	}
+}
+
 func main() {
+	saveDir := flag.String("save", "", "directorio donde guardar los modelos entrenados (vacío para no guardarlos)")
+	epochs := flag.Int("epocas", 50, "número máximo de épocas de entrenamiento")
+	patience := flag.Int("paciencia", 5, "épocas sin mejorar el RMSE de validación antes de parar (0 para no parar antes)")
+	validation := flag.Float64("validacion", 0.1, "fracción final del conjunto de entrenamiento reservada para la validación y la parada temprana (0 para no reservar)")
+	metricsPath := flag.String("metricas", "", "archivo CSV donde escribir las métricas de cada época (vacío para no escribirlas)")
+	flag.Parse()
+
 	// Cargar los datos
 	ratings, err := preprocess.LoadData("ratings.dat")
 	if err != nil {
@@ -21,27 +44,72 @@ func main() {
 
 	// Dividir los datos en entrenamiento y prueba
 	trainSet, testSet := preprocess.SplitData(ratings, 0.8)
+	var validationSet []preprocess.Rating
+	if *validation > 0 {
+		trainSet, validationSet = preprocess.SplitData(trainSet, 1-*validation)
+	}
+
+	var epochMetrics *metrics.CSVWriter
+	if *metricsPath != "" {
+		epochMetrics, err = metrics.Create(*metricsPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer func() {
+			if err := epochMetrics.Close(); err != nil {
+				fmt.Println("Error al escribir las métricas:", err)
+				return
+			}
+			fmt.Println("Métricas por época guardadas en", *metricsPath)
+		}()
+	}
+	seqConfig := sequential.Config{Epochs: *epochs, Patience: *patience, Observer: epochMetrics.Run("mf_secuencial"), Validation: validationSet}
+	concConfig := concurrent.Config{Epochs: *epochs, Patience: *patience, Observer: epochMetrics.Run("mf_concurrente"), Validation: validationSet}
 
 	numUsers := 6040
 	numMovies := 3952
 
 	// Entrenamiento secuencial
 	start := time.Now()
-	sequential.TrainSequential(trainSet, numUsers, numMovies)
+	bestEpoch := sequential.TrainSequential(trainSet, numUsers, numMovies, seed, seqConfig)
 	duration := time.Since(start)
 	fmt.Println("Tiempo de entrenamiento secuencial:", duration)
+	printBestEpoch(bestEpoch, *epochs)
 
 	// Evaluación del modelo secuencial
 	mseSequential := sequential.EvaluateSequential(testSet)
 	fmt.Printf("Error cuadrático medio secuencial: %.4f\n", mseSequential)
+	if *saveDir != "" {
+		persist.SaveAll(sequential.SaveModel, *saveDir, "mf_secuencial")
+	}
 
 	// Entrenamiento concurrente
 	start = time.Now()
-	concurrent.TrainConcurrent(trainSet, numUsers, numMovies)
+	bestEpoch = concurrent.TrainConcurrent(trainSet, numUsers, numMovies, seed, concConfig)
 	duration = time.Since(start)
 	fmt.Println("Tiempo de entrenamiento concurrente:", duration)
+	printBestEpoch(bestEpoch, *epochs)
 
 	// Evaluación del modelo concurrente
 	mseConcurrent := concurrent.EvaluateConcurrent(testSet)
 	fmt.Printf("Error cuadrático medio concurrente: %.4f\n", mseConcurrent)
+	if *saveDir != "" {
+		persist.SaveAll(concurrent.SaveModel, *saveDir, "mf_concurrente")
+	}
+
+	// Entrenamiento concurrente Hogwild! (sin mutex)
+	concConfig.Observer = epochMetrics.Run("mf_hogwild")
+	start = time.Now()
+	bestEpoch = concurrent.TrainHogwild(trainSet, numUsers, numMovies, seed, hogwildWorkers, concConfig)
+	duration = time.Since(start)
+	fmt.Println("Tiempo de entrenamiento Hogwild!:", duration)
+	printBestEpoch(bestEpoch, *epochs)
+
+	// Evaluación del modelo Hogwild!
+	mseHogwild := concurrent.EvaluateConcurrent(testSet)
+	fmt.Printf("Error cuadrático medio Hogwild!: %.4f\n", mseHogwild)
+	if *saveDir != "" {
+		persist.SaveAll(concurrent.SaveModel, *saveDir, "mf_hogwild")
+	}
 }
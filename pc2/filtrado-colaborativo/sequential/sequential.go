@@ -1,15 +1,18 @@
 package sequential
 
 import (
+	"adult/metrics"
+	"adult/persist"
 	"filtrado/preprocess"
+	"fmt"
 	"math"
 	"math/rand"
+	"slices"
 	"time"
 )
 
 // Factores Latentes
-const K = 10 // Número de factores latentes
-const epochs = 50
+const K = 10        // Número de factores latentes
 const alpha = 0.01  // Tasa de aprendizaje
 const lambda = 0.02 // Regularización
 
@@ -17,36 +20,48 @@ const lambda = 0.02 // Regularización
 var P [][]float64
 var Q [][]float64
 
-// Inicializa las matrices P y Q
-func InitializeMatrices(numUsers, numMovies int) {
-	rand.Seed(time.Now().UnixNano())
+// Configuración del entrenamiento
+type Config struct {
+	Epochs     int                 // Número máximo de épocas
+	Patience   int                 // Épocas sin mejorar el RMSE de validación antes de parar (<= 0 para no parar antes)
+	Observer   metrics.Observer    // Recibe el RMSE de entrenamiento y de validación de cada época (nil para no informar)
+	Validation []preprocess.Rating // Calificaciones de la parada temprana (vacío para entrenar todas las épocas)
+}
+
+// Inicializa las matrices P y Q con un generador propio (la semilla hace reproducible el entrenamiento)
+func InitializeMatrices(numUsers, numMovies int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
 	P = make([][]float64, numUsers+1)
 	Q = make([][]float64, numMovies+1)
 
 	for i := range P {
 		P[i] = make([]float64, K)
 		for k := range P[i] {
-			P[i][k] = rand.Float64()
+			P[i][k] = rng.Float64()
 		}
 	}
 
 	for i := range Q {
 		Q[i] = make([]float64, K)
 		for k := range Q[i] {
-			Q[i][k] = rand.Float64()
+			Q[i][k] = rng.Float64()
 		}
 	}
 }
 
-// Entrenamiento secuencial usando filtrado colaborativo
-func TrainSequential(ratings []preprocess.Rating, numUsers, numMovies int) {
-	InitializeMatrices(numUsers, numMovies)
+// Entrenamiento secuencial usando filtrado colaborativo; devuelve la época cuyos factores se conservaron (0 sin validación)
+func TrainSequential(ratings []preprocess.Rating, numUsers, numMovies int, seed int64, config Config) int {
+	InitializeMatrices(numUsers, numMovies, seed)
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(config.Patience)
 
-	for epoch := 0; epoch < epochs; epoch++ {
+	for epoch := 0; epoch < config.Epochs; epoch++ {
+		var squaredErr float64 // Error de cada calificación antes de su actualización
 		for _, r := range ratings {
 			user, movie, rating := r.UserID, r.MovieID, r.Rating
 			pred := PredictRating(user, movie)
 			err := rating - pred
+			squaredErr += err * err
 
 			// Actualización de P y Q
 			for k := 0; k < K; k++ {
@@ -54,7 +69,12 @@ func TrainSequential(ratings []preprocess.Rating, numUsers, numMovies int) {
 				Q[movie][k] += alpha * (err*P[user][k] - lambda*Q[movie][k])
 			}
 		}
+		m := metrics.Report([]metrics.Observer{config.Observer}, epoch+1, math.Sqrt(squaredErr/float64(len(ratings))), start, config.Validation, validationRMSE)
+		if len(config.Validation) > 0 && stopping.Check(m, snapshot) {
+			break
+		}
 	}
+	return stopping.Restore()
 }
 
 // Predice la calificación de un usuario a una película
@@ -76,3 +96,54 @@ func EvaluateSequential(testSet []preprocess.Rating) float64 {
 	}
 	return mse / float64(len(testSet))
 }
+
+// Función para calcular el RMSE de validación (la factorización no clasifica, así que no hay precisión)
+func validationRMSE(ratings []preprocess.Rating) (float64, float64) {
+	return math.Sqrt(EvaluateSequential(ratings)), math.NaN()
+}
+
+// Función para copiar P y Q y devolver la función que los restaura (parada temprana)
+func snapshot() func() {
+	p, q := cloneMatrix(P), cloneMatrix(Q)
+	return func() { P, Q = p, q }
+}
+
+// Copia profunda de una matriz de factores
+func cloneMatrix(m [][]float64) [][]float64 {
+	clone := make([][]float64, len(m))
+	for i := range m {
+		clone[i] = slices.Clone(m[i])
+	}
+	return clone
+}
+
+// Tipo de modelo en los archivos guardados (el mismo en la versión secuencial y la concurrente)
+const modelKind = "matrix-factorization"
+
+// Versión del formato de la factorización en disco
+const modelVersion = 1
+
+// Factores latentes tal como se guardan en disco
+type savedModel struct {
+	K int
+	P [][]float64
+	Q [][]float64
+}
+
+// Guarda las matrices P y Q en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func SaveModel(path string) error {
+	return persist.Save(path, modelKind, modelVersion, savedModel{K: K, P: P, Q: Q})
+}
+
+// Carga las matrices P y Q guardadas con SaveModel
+func LoadModel(path string) error {
+	var model savedModel
+	if err := persist.Load(path, modelKind, modelVersion, &model); err != nil {
+		return err
+	}
+	if model.K != K {
+		return fmt.Errorf("el modelo tiene %d factores latentes, se esperaban %d", model.K, K)
+	}
+	P, Q = model.P, model.Q
+	return nil
+}
@@ -1,263 +1,220 @@
 package concurrent
 
 import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/persist"
+	"adult/preprocess"
+	"adult/random"
 	"fmt"
-	"math/rand"
-	"rf/preprocess"
+	"rf/tree"
 	"sync"
 	"time"
 )
 
-// Estructura para representar un árbol de decisión (nodo)
-type DecisionTree struct {
-	SplitFeature int
-	Threshold    float64
-	Left         *DecisionTree
-	Right        *DecisionTree
-	Prediction   string
-}
+// Tamaño por defecto del pool de workers
+const defaultWorkers = 4
+
+// El bosque implementa la interfaz común de clasificadores
+var _ classifier.Classifier = (*RandomForest)(nil)
 
 // Estructura del modelo Random Forest concurrente
 type RandomForest struct {
-	Trees []*DecisionTree
-	mu    sync.Mutex // Mutex para evitar condición de carrera
+	Trees       []*tree.DecisionTree
+	Encoder     *encoding.Encoder // Codificador de características ajustado con los datos de entrenamiento
+	NumTrees    int               // Número de árboles que construye Fit
+	TreeConfig  tree.Config       // Parámetros de construcción de cada árbol (profundidad, criterio, mtry)
+	Seed        int64             // Semilla de la que se deriva el flujo aleatorio de cada árbol
+	OOBAccuracy float64           // Precisión fuera de bolsa calculada al final de Fit
+	TreeWeights []float64         // Peso del voto de cada árbol (nil: todos pesan lo mismo); Fit lo reinicia
+	Workers     int               // Tamaño del pool de workers
+	inBag       [][]bool          // Filas de entrenamiento que entraron en la muestra de cada árbol
 }
 
-// Función para entrenar el modelo Random Forest concurrentemente con pool de workers
-func TrainRandomForest(records []preprocess.Record, numTrees int, maxDepth int) *RandomForest {
-	var forest RandomForest
-	rand.Seed(time.Now().UnixNano())
-
-	// Canal para recibir los árboles construidos en paralelo
-	treeChan := make(chan *DecisionTree, numTrees)
-	workerChan := make(chan int, 4) // Limitar el número de workers concurrentes (pool de 4)
-	var wg sync.WaitGroup
-
-	// Iniciar el entrenamiento de los árboles concurrentemente
-	for i := 0; i < numTrees; i++ {
-		workerChan <- i // Limitar a 4 goroutines concurrentes
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			sample := bootstrapSample(records)
-			tree := buildTree(sample, maxDepth)
-			treeChan <- tree
-			<-workerChan // Liberar espacio en el worker pool
-		}()
-	}
-
-	// Goroutine para cerrar el canal cuando todos los árboles estén listos
-	go func() {
-		wg.Wait()
-		close(treeChan)
-	}()
+// Función para crear un Random Forest sin entrenar
+func NewRandomForest(encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	return &RandomForest{Encoder: encoder, NumTrees: numTrees, TreeConfig: tree.Config{MaxDepth: maxDepth, Criterion: tree.Gini}, Seed: seed, Workers: defaultWorkers}
+}
 
-	// Recibir los árboles construidos
-	for tree := range treeChan {
-		forest.mu.Lock()
-		forest.Trees = append(forest.Trees, tree)
-		forest.mu.Unlock() // Evitar condición de carrera
-	}
+// Función para entrenar el modelo Random Forest concurrentemente con un pool de workers compartido
+func TrainRandomForest(records []preprocess.Record, encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	forest := NewRandomForest(encoder, numTrees, maxDepth, seed)
+	forest.Fit(records)
+	return forest
+}
 
-	return &forest
+// Función para crear un bosque de árboles extremadamente aleatorios (ExtraTrees) sin entrenar:
+// cortes al azar en cada característica candidata y todas las filas en cada árbol
+func NewExtraTrees(encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	forest := NewRandomForest(encoder, numTrees, maxDepth, seed)
+	forest.TreeConfig.ExtraTrees = true
+	return forest
 }
 
-// Función para realizar predicciones de manera concurrente
-func (forest *RandomForest) Predict(record preprocess.Record) string {
-	votes := make(map[string]int)
-	var mu sync.Mutex
+// Función para entrenar un bosque ExtraTrees
+func TrainExtraTrees(records []preprocess.Record, encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	forest := NewExtraTrees(encoder, numTrees, maxDepth, seed)
+	forest.Fit(records)
+	return forest
+}
 
-	var wg sync.WaitGroup
-	for _, tree := range forest.Trees {
-		wg.Add(1)
-		go func(tree *DecisionTree) {
-			defer wg.Done()
-			prediction := tree.predict(record)
-			mu.Lock()
-			votes[prediction]++
-			mu.Unlock() // Proteger el acceso concurrente al mapa de votos
-		}(tree)
+// Función para construir los árboles del bosque con los registros (implementa classifier.Classifier)
+func (forest *RandomForest) Fit(records []preprocess.Record) error {
+	if len(records) == 0 {
+		return classifier.ErrNoRecords
 	}
-
-	wg.Wait()
-
-	// Devolver el voto mayoritario
-	var maxVotes int
-	var majorityLabel string
-	for label, count := range votes {
-		if count > maxVotes {
-			maxVotes = count
-			majorityLabel = label
+	trainer, err := tree.NewTrainer(records, forest.Encoder, forest.TreeConfig)
+	if err != nil {
+		return err
+	}
+	numTrees, seed := forest.NumTrees, forest.Seed
+
+	// Un solo pool acotado reparte tanto los árboles como el trabajo dentro de cada árbol
+	// (evaluación de características y subárboles de los nodos grandes)
+	pool := tree.NewPool(forest.Workers)
+	forest.Trees = make([]*tree.DecisionTree, numTrees)
+	forest.TreeWeights = nil // Los pesos anteriores correspondían a otros árboles
+	forest.inBag = make([][]bool, numTrees)
+	tasks := make([]func(), numTrees)
+	for i := range tasks {
+		tasks[i] = func() {
+			rng := random.Derive(seed, i)                                   // Mismo flujo que el árbol i de la versión secuencial
+			forest.Trees[i], forest.inBag[i] = trainer.BuildTree(rng, pool) // Cada tarea escribe solo su posición
 		}
 	}
+	pool.Run(tasks...)
 
-	return majorityLabel
-}
-
-// Función recursiva para construir un árbol de decisión (igual que en la versión secuencial)
-func buildTree(records []preprocess.Record, depth int) *DecisionTree {
-	if depth == 0 || len(records) == 0 {
-		return &DecisionTree{Prediction: majorityLabel(records)}
+	forest.OOBAccuracy = 0 // Sin bootstrap no hay filas fuera de bolsa
+	if forest.TreeConfig.Bootstrap() {
+		// Estimar la generalización con las filas que cada árbol no vio (repartidas entre los workers)
+		forest.OOBAccuracy = tree.OOBAccuracy(forest.Trees, forest.TreeWeights, forest.inBag, records, forest.Encoder, pool)
 	}
 
-	feature, threshold := chooseBestSplit(records)
-	leftRecords, rightRecords := splitRecords(records, feature, threshold)
+	return nil
+}
 
-	leftChild := buildTree(leftRecords, depth-1)
-	rightChild := buildTree(rightRecords, depth-1)
+// Función para realizar predicciones: clase más probable (el empate va a la clase negativa)
+func (forest *RandomForest) Predict(record preprocess.Record) string {
+	return classifier.LabelFromProba(forest.PredictProba(record))
+}
 
-	return &DecisionTree{
-		SplitFeature: feature,
-		Threshold:    threshold,
-		Left:         leftChild,
-		Right:        rightChild,
-	}
+// Función para estimar la probabilidad de la clase positiva de un registro: media (ponderada por TreeWeights)
+// de la distribución de clases de la hoja de cada árbol. Un solo registro se recorre en la goroutine actual;
+// la concurrencia está en PredictBatch, que reparte registros y no árboles
+func (forest *RandomForest) PredictProba(record preprocess.Record) float64 {
+	features := tree.FeatureVector(record, forest.Encoder)
+	return tree.EnsembleProba(forest.Trees, forest.TreeWeights, features)
 }
 
-// Función para hacer predicciones con un árbol de decisión
-func (tree *DecisionTree) predict(record preprocess.Record) string {
-	if tree.Prediction != "" {
-		return tree.Prediction
-	}
+// Registros que toma cada worker de una vez al predecir por lotes
+const batchChunkSize = 1024
 
-	var featureValue float64
-	switch tree.SplitFeature {
-	case 0:
-		featureValue = float64(record.Age)
-	case 2:
-		featureValue = float64(record.Fnlwgt)
-	case 4:
-		featureValue = float64(record.EducationNum)
-	case 10:
-		featureValue = float64(record.CapitalGain)
-	case 11:
-		featureValue = float64(record.CapitalLoss)
-	case 12:
-		featureValue = float64(record.HoursPerWeek)
-	default:
-		featureValue = 0
-	}
+// Función para estimar la probabilidad de la clase positiva de varios registros con un pool fijo de workers:
+// cada worker toma bloques de registros y recorre todos los árboles sin bloqueos (los árboles son de solo lectura)
+func (forest *RandomForest) PredictProbaBatch(records []preprocess.Record) []float64 {
+	probas := make([]float64, len(records))
+	workers := max(1, forest.Workers)
+	chunks := make(chan int, workers)
+	var wg sync.WaitGroup
 
-	if featureValue < tree.Threshold {
-		return tree.Left.predict(record)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				end := min(start+batchChunkSize, len(records))
+				for i := start; i < end; i++ {
+					probas[i] = forest.PredictProba(records[i]) // Cada registro lo escribe un solo worker
+				}
+			}
+		}()
 	}
-	return tree.Right.predict(record)
-}
-
-// Elegir el mejor punto de división basado en los datos reales
-func chooseBestSplit(records []preprocess.Record) (int, float64) {
-	bestFeature := rand.Intn(6) // Elegimos características numéricas
-	bestThreshold := 0.0
 
-	// Para simplificar, elegimos el valor promedio como umbral para la característica seleccionada
-	sum := 0.0
-	for _, record := range records {
-		var featureValue float64
-		switch bestFeature {
-		case 0:
-			featureValue = float64(record.Age)
-		case 2:
-			featureValue = float64(record.Fnlwgt)
-		case 4:
-			featureValue = float64(record.EducationNum)
-		case 10:
-			featureValue = float64(record.CapitalGain)
-		case 11:
-			featureValue = float64(record.CapitalLoss)
-		case 12:
-			featureValue = float64(record.HoursPerWeek)
-		}
-		sum += featureValue
+	for start := 0; start < len(records); start += batchChunkSize {
+		chunks <- start
 	}
-	bestThreshold = sum / float64(len(records)) // Promedio
+	close(chunks)
+	wg.Wait()
 
-	return bestFeature, bestThreshold
+	return probas
 }
 
-// Dividir los registros en dos subconjuntos según la característica y el umbral
-func splitRecords(records []preprocess.Record, feature int, threshold float64) ([]preprocess.Record, []preprocess.Record) {
-	var left, right []preprocess.Record
-
-	for _, record := range records {
-		var featureValue float64
-		switch feature {
-		case 0:
-			featureValue = float64(record.Age)
-		case 2:
-			featureValue = float64(record.Fnlwgt)
-		case 4:
-			featureValue = float64(record.EducationNum)
-		case 10:
-			featureValue = float64(record.CapitalGain)
-		case 11:
-			featureValue = float64(record.CapitalLoss)
-		case 12:
-			featureValue = float64(record.HoursPerWeek)
-		}
+// Función para predecir las etiquetas de varios registros repartidos entre los workers
+func (forest *RandomForest) PredictBatch(records []preprocess.Record) []string {
+	predictions := make([]string, len(records))
+	for i, proba := range forest.PredictProbaBatch(records) {
+		predictions[i] = classifier.LabelFromProba(proba)
+	}
+	return predictions
+}
 
-		if featureValue < threshold {
-			left = append(left, record)
-		} else {
-			right = append(right, record)
-		}
+// Fijar el peso del voto de cada árbol (nil vuelve a los pesos iguales)
+func (forest *RandomForest) SetTreeWeights(weights []float64) error {
+	if err := tree.ValidateWeights(weights, len(forest.Trees)); err != nil {
+		return err
 	}
+	forest.TreeWeights = weights
+	return nil
+}
 
-	return left, right
+// Importancia de cada característica (indexada por preprocess.Feature) según la reducción de impureza de sus divisiones
+func (forest *RandomForest) ImpurityImportance() []float64 {
+	return tree.ImpurityImportance(forest.Trees, forest.TreeConfig.Criterion)
 }
 
-// Función de votación para determinar la etiqueta mayoritaria
-func majorityLabel(records []preprocess.Record) string {
-	labelCount := make(map[string]int)
+// Importancia de cada característica según la caída de la precisión sobre los registros al permutarla (una característica por worker)
+func (forest *RandomForest) PermutationImportance(records []preprocess.Record) []float64 {
+	return tree.PermutationImportance(forest.Trees, forest.TreeWeights, records, forest.Encoder, forest.Seed, tree.NewPool(forest.Workers))
+}
 
-	for _, record := range records {
-		labelCount[record.Income]++
-	}
+// Tipo de modelo en los archivos guardados (el mismo que en la versión secuencial, son intercambiables)
+const modelKind = "random-forest"
 
-	var maxCount int
-	var majorityLabel string
-	for label, count := range labelCount {
-		if count > maxCount {
-			maxCount = count
-			majorityLabel = label
-		}
-	}
+// Versión del formato del bosque en disco (2: los árboles dividen por características de Record con nodos
+// categóricos en lugar de por índices del codificador)
+const modelVersion = 2
 
-	return majorityLabel
+// Guardar el bosque (árboles y codificador) en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func (forest *RandomForest) Save(path string) error {
+	return persist.Save(path, modelKind, modelVersion, forest)
 }
 
-// Bootstrap sample: obtener una muestra aleatoria con reemplazo
-func bootstrapSample(records []preprocess.Record) []preprocess.Record {
-	var sample []preprocess.Record
-	for i := 0; i < len(records); i++ {
-		sample = append(sample, records[rand.Intn(len(records))])
+// Cargar un modelo guardado con Save
+func Load(path string) (*RandomForest, error) {
+	forest := &RandomForest{}
+	if err := persist.Load(path, modelKind, modelVersion, forest); err != nil {
+		return nil, err
+	}
+	if forest.Workers <= 0 {
+		forest.Workers = defaultWorkers
 	}
-	return sample
+	return forest, nil
 }
 
-// Función para probar el Random Forest concurrente
-func TestConcurrentRandomForest(records []preprocess.Record) {
-	// Dividir datos en entrenamiento y prueba (80% entrenamiento, 20% prueba)
-	numTrain := int(0.8 * float64(len(records)))
-	trainData := records[:numTrain]
-	testData := records[numTrain:]
-
+// Función para probar el Random Forest concurrente con la configuración de árbol dada (devuelve el modelo entrenado)
+func TestConcurrentRandomForest(trainData, testData []preprocess.Record, seed int64, config tree.Config) *RandomForest {
 	// Entrenar Random Forest concurrente
 	fmt.Println("Entrenando Random Forest Concurrente...")
+	encoder := encoding.Fit(trainData, encoding.Ordinal)      // Los árboles trabajan bien con el índice de la categoría
+	rf := NewRandomForest(encoder, 10, config.MaxDepth, seed) // 10 árboles
+	rf.TreeConfig = config                                    // Modo (exacto o histograma, bagging o ExtraTrees) y profundidad
+
 	start := time.Now()
-	rf := TrainRandomForest(trainData, 10, 5) // 10 árboles, profundidad máxima 5
+	if err := rf.Fit(trainData); err != nil {
+		fmt.Printf("Error al entrenar: %v\n", err)
+		return nil
+	}
 	elapsed := time.Since(start)
 	fmt.Printf("Tiempo de entrenamiento: %s\n", elapsed)
+	if rf.TreeConfig.Bootstrap() {
+		fmt.Printf("Precisión fuera de bolsa: %.2f%%\n", rf.OOBAccuracy*100)
+	}
 
 	// Probar el modelo
 	fmt.Println("Probando Random Forest Concurrente...")
-	correct := 0
-	for _, record := range testData {
-		prediction := rf.Predict(record)
-		if prediction == record.Income {
-			correct++
-		}
-	}
-
-	accuracy := float64(correct) / float64(len(testData))
+	accuracy := classifier.Accuracy(rf, testData)
 	fmt.Printf("Precisión: %.2f%%\n", accuracy*100)
+	fmt.Printf("AUC: %.4f\n", classifier.AUC(classifier.ROC(rf, testData)))
+
+	return rf
 }
@@ -0,0 +1,153 @@
+package concurrent
+
+import (
+	"adult/encoding"
+	"adult/preprocess"
+	"fmt"
+	"reflect"
+	"rf/sequential"
+	"rf/tree"
+	"slices"
+	"testing"
+)
+
+// Semilla fija de las pruebas
+const testSeed = 42
+
+// Registros de entrenamiento de las pruebas (los primeros del archivo, sin aumentar)
+const testRecords = 4000
+
+// Con la misma semilla el bosque concurrente debe construir los mismos árboles, la misma precisión
+// fuera de bolsa y las mismas predicciones que el secuencial, con cualquier número de workers
+func TestRandomForestMatchesSequential(t *testing.T) {
+	records, err := preprocess.ReadRecords("../adult.data", testRecords)
+	if err != nil {
+		t.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.Ordinal)
+
+	tests := []struct {
+		name   string
+		config tree.Config
+	}{
+		{"exacto gini", tree.Config{MaxDepth: 6, Criterion: tree.Gini}},
+		{"exacto entropía", tree.Config{MaxDepth: 6, Criterion: tree.Entropy, MinSamplesLeaf: 5}},
+		{"histograma", tree.Config{MaxDepth: 8, Criterion: tree.Gini, Histogram: true, MaxBins: 32}},
+		{"extratrees", tree.Config{MaxDepth: 8, Criterion: tree.Gini, ExtraTrees: true}},
+		{"extratrees con bootstrap", tree.Config{MaxDepth: 6, Criterion: tree.Gini, Histogram: true, ExtraTrees: true, Sampling: tree.BootstrapSampling}},
+		{"poda", tree.Config{MaxDepth: 10, Criterion: tree.Gini, MaxLeafNodes: 12, CCPAlpha: 1e-4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := sequential.NewRandomForest(encoder, 6, tt.config.MaxDepth, testSeed)
+			seq.TreeConfig = tt.config
+			if err := seq.Fit(records); err != nil {
+				t.Fatalf("Fit secuencial: %v", err)
+			}
+			want := seq.PredictProbaBatch(records)
+
+			for _, workers := range []int{-1, 1, 3, 8} {
+				conc := NewRandomForest(encoder, 6, tt.config.MaxDepth, testSeed)
+				conc.TreeConfig, conc.Workers = tt.config, workers
+				if err := conc.Fit(records); err != nil {
+					t.Fatalf("Fit concurrente (%d workers): %v", workers, err)
+				}
+				if !reflect.DeepEqual(conc.Trees, seq.Trees) {
+					t.Errorf("%d workers: los árboles no coinciden con los de la versión secuencial", workers)
+				}
+				if conc.OOBAccuracy != seq.OOBAccuracy {
+					t.Errorf("%d workers: precisión fuera de bolsa %v, se esperaba %v", workers, conc.OOBAccuracy, seq.OOBAccuracy)
+				}
+				if got := conc.PredictProbaBatch(records); !slices.Equal(got, want) {
+					t.Errorf("%d workers: las probabilidades no coinciden con las de la versión secuencial", workers)
+				}
+				if got := conc.PredictBatch(records); !slices.Equal(got, seq.PredictBatch(records)) {
+					t.Errorf("%d workers: las predicciones no coinciden con las de la versión secuencial", workers)
+				}
+			}
+		})
+	}
+}
+
+// Con la misma semilla el boosting concurrente debe conservar los mismos árboles y la misma pérdida
+// de validación que el secuencial, con cualquier número de workers
+func TestGradientBoostingMatchesSequential(t *testing.T) {
+	records, err := preprocess.ReadRecords("../adult.data", testRecords)
+	if err != nil {
+		t.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.Ordinal)
+
+	tests := []struct {
+		name      string
+		rounds    int
+		maxDepth  int
+		subsample float64
+		patience  int
+	}{
+		{"submuestra", 20, 3, 0.8, 10},
+		{"todas las filas", 20, 4, 1, 10},
+		{"parada temprana", 60, 6, 0.5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := sequential.NewGradientBoosting(encoder, tt.rounds, tt.maxDepth, 0.3, testSeed)
+			seq.Subsample, seq.Patience = tt.subsample, tt.patience
+			if err := seq.Fit(records); err != nil {
+				t.Fatalf("Fit secuencial: %v", err)
+			}
+
+			for _, workers := range []int{1, 3, 8} {
+				conc := NewGradientBoosting(encoder, tt.rounds, tt.maxDepth, 0.3, testSeed)
+				conc.Subsample, conc.Patience, conc.Workers = tt.subsample, tt.patience, workers
+				if err := conc.Fit(records); err != nil {
+					t.Fatalf("Fit concurrente (%d workers): %v", workers, err)
+				}
+				if !reflect.DeepEqual(conc.Trees, seq.Trees) {
+					t.Errorf("%d workers: los árboles no coinciden con los de la versión secuencial", workers)
+				}
+				if conc.InitialScore != seq.InitialScore || conc.ValidationLoss != seq.ValidationLoss {
+					t.Errorf("%d workers: logit inicial %v y pérdida %v, se esperaba %v y %v",
+						workers, conc.InitialScore, conc.ValidationLoss, seq.InitialScore, seq.ValidationLoss)
+				}
+				if got := conc.PredictBatch(records); !slices.Equal(got, seq.PredictBatch(records)) {
+					t.Errorf("%d workers: las predicciones no coinciden con las de la versión secuencial", workers)
+				}
+			}
+		})
+	}
+}
+
+// Predicción por lotes de la versión secuencial frente a la concurrente con varios tamaños de pool,
+// con los mismos árboles y registros
+func BenchmarkPredictBatch(b *testing.B) {
+	records, err := preprocess.ReadRecords("../adult.data", testRecords)
+	if err != nil {
+		b.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.Ordinal)
+	config := tree.Config{MaxDepth: 8, Criterion: tree.Gini, Histogram: true}
+	seq := sequential.NewRandomForest(encoder, 10, config.MaxDepth, testSeed)
+	seq.TreeConfig = config
+	if err := seq.Fit(records); err != nil {
+		b.Fatalf("Fit secuencial: %v", err)
+	}
+	batch, err := preprocess.ReadRecords("../adult.data", 20000)
+	if err != nil {
+		b.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+
+	b.Run("secuencial", func(b *testing.B) {
+		for range b.N {
+			seq.PredictBatch(batch)
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		conc := &RandomForest{Trees: seq.Trees, Encoder: encoder, TreeConfig: config, Workers: workers}
+		b.Run(fmt.Sprintf("concurrente/workers=%d", workers), func(b *testing.B) {
+			for range b.N {
+				conc.PredictBatch(batch)
+			}
+		})
+	}
+}
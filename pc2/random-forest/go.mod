@@ -1,3 +1,7 @@
 module rf
 
 go 1.23.0
+
+require adult v0.0.0
+
+replace adult => ../adult
@@ -1,28 +1,140 @@
 package main
 
 import (
+	"adult/encoding"
+	"adult/persist"
+	"adult/preprocess"
+	"cmp"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"rf/concurrent"
-	"rf/preprocess"
 	"rf/sequential"
+	"rf/tree"
+	"slices"
 )
 
+// Semilla para que las ejecuciones sean reproducibles
+const seed = 42
+
+// Exportar un árbol del bosque a Graphviz DOT (.dot) y como reglas if/else (.txt)
+func exportTree(trees []*tree.DecisionTree, encoder *encoding.Encoder, index int, dir, name string) {
+	if index < 0 || index >= len(trees) {
+		fmt.Printf("El bosque no tiene el árbol %d (tiene %d árboles)\n", index, len(trees))
+		return
+	}
+	dt := trees[index]
+	exporters := []struct {
+		ext   string
+		write func(io.Writer, *encoding.Encoder) error
+	}{{".dot", dt.WriteDOT}, {".txt", dt.WriteRules}}
+
+	for _, exporter := range exporters {
+		path := filepath.Join(dir, fmt.Sprintf("%s_arbol%d%s", name, index, exporter.ext))
+		file, err := os.Create(path)
+		if err != nil {
+			fmt.Printf("Error al exportar el árbol: %v\n", err)
+			return
+		}
+		err = exporter.write(file, encoder)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Printf("Error al exportar el árbol: %v\n", err)
+			return
+		}
+		fmt.Printf("Árbol exportado en %s\n", path)
+	}
+}
+
+// Modelo que informa de la importancia de sus características
+type importanceReporter interface {
+	ImpurityImportance() []float64
+	PermutationImportance(records []preprocess.Record) []float64
+}
+
+// Mostrar la importancia por impureza y por permutación (sobre los registros dados) de cada característica
+func printImportance(model importanceReporter, records []preprocess.Record) {
+	impurity := model.ImpurityImportance()
+	permutation := model.PermutationImportance(records)
+
+	// Ordenar de mayor a menor importancia por permutación
+	features := slices.Clone(preprocess.AllFeatures)
+	slices.SortStableFunc(features, func(a, b preprocess.Feature) int {
+		return cmp.Compare(permutation[b], permutation[a])
+	})
+
+	fmt.Println("Importancia de las características (impureza / permutación):")
+	for _, feature := range features {
+		fmt.Printf("  %-15s %.4f  %+.4f\n", feature, impurity[feature], permutation[feature])
+	}
+}
+
 func main() {
+	saveDir := flag.String("save", "", "directorio donde guardar los modelos entrenados (vacío para no guardarlos)")
+	histogram := flag.Bool("histograma", true, "construir los árboles con histogramas de columnas cuantizadas (false para el modo exacto)")
+	extraTrees := flag.Bool("extratrees", false, "construir árboles extremadamente aleatorios (cortes al azar, sin bootstrap) en lugar de bagging")
+	exportDir := flag.String("exportar", "", "directorio donde exportar un árbol del bosque en DOT y como reglas de texto (vacío para no exportarlo)")
+	treeIndex := flag.Int("arbol", 0, "índice del árbol del bosque que se exporta con -exportar")
+	flag.Parse()
+
+	// Configuración de cada árbol del bosque
+	config := tree.Config{MaxDepth: 5, Criterion: tree.Gini, Histogram: *histogram, ExtraTrees: *extraTrees}
+
 	// Cargar y preprocesar los datos
 	fmt.Println("Cargando y preprocesando datos...")
-	records, err := preprocess.LoadAndPreprocess("adult.data", 1000000) // Cargar 1 millón de registros
+	records, err := preprocess.LoadAndPreprocess("adult.data", 1000000, seed) // Cargar 1 millón de registros
 	if err != nil {
 		fmt.Printf("Error al cargar los datos: %v\n", err)
 		return
 	}
 
-	fmt.Println("Datos cargados correctamente.")
+	// Cargar el conjunto de prueba oficial para que la precisión sea comparable con los resultados publicados
+	testRecords, err := preprocess.LoadTestData("adult.test")
+	if err != nil {
+		fmt.Printf("Error al cargar los datos de prueba: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Datos cargados correctamente (%d de entrenamiento, %d de prueba).\n", len(records), len(testRecords))
 
 	// **Versión secuencial**
 	fmt.Println("\n--- Random Forest Secuencial ---")
-	sequential.TestSequentialRandomForest(records)
+	seqModel := sequential.TestSequentialRandomForest(records, testRecords, seed, config)
+	if seqModel != nil {
+		printImportance(seqModel, testRecords)
+		if *saveDir != "" {
+			persist.SaveAll(seqModel.Save, *saveDir, "rf_secuencial")
+		}
+	}
 
 	// **Versión concurrente**
 	fmt.Println("\n--- Random Forest Concurrente ---")
-	concurrent.TestConcurrentRandomForest(records)
+	concModel := concurrent.TestConcurrentRandomForest(records, testRecords, seed, config)
+	if concModel != nil {
+		printImportance(concModel, testRecords)
+		if *exportDir != "" {
+			exportTree(concModel.Trees, concModel.Encoder, *treeIndex, *exportDir, "rf_concurrente")
+		}
+		if *saveDir != "" {
+			persist.SaveAll(concModel.Save, *saveDir, "rf_concurrente")
+		}
+	}
+
+	// **Gradient boosting secuencial**
+	fmt.Println("\n--- Gradient Boosting Secuencial ---")
+	seqBoosting := sequential.TestSequentialGradientBoosting(records, testRecords, seed)
+	if seqBoosting != nil && *saveDir != "" {
+		persist.SaveAll(seqBoosting.Save, *saveDir, "gb_secuencial")
+	}
+
+	// **Gradient boosting concurrente**
+	fmt.Println("\n--- Gradient Boosting Concurrente ---")
+	concBoosting := concurrent.TestConcurrentGradientBoosting(records, testRecords, seed)
+	if concBoosting != nil && *saveDir != "" {
+		persist.SaveAll(concBoosting.Save, *saveDir, "gb_concurrente")
+	}
 }
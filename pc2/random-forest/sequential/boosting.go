@@ -0,0 +1,200 @@
+package sequential
+
+import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/metrics"
+	"adult/persist"
+	"adult/preprocess"
+	"adult/random"
+	"fmt"
+	"math"
+	"rf/tree"
+	"time"
+)
+
+// El modelo de boosting implementa la interfaz común de clasificadores
+var _ classifier.Classifier = (*GradientBoosting)(nil)
+
+// Estructura del modelo de gradient boosting con pérdida logística
+type GradientBoosting struct {
+	Trees              []*tree.DecisionTree
+	InitialScore       float64           // Logit inicial: log(p / (1 - p)) con la proporción de positivos
+	Encoder            *encoding.Encoder // Codificador de características ajustado con los datos de entrenamiento
+	NumRounds          int               // Número máximo de rondas (un árbol por ronda)
+	LearningRate       float64           // Shrinkage aplicado al valor de cada hoja
+	Subsample          float64           // Fracción de filas que usa cada ronda (sin reemplazo)
+	Lambda             float64           // Regularización L2 de los valores de las hojas
+	ValidationFraction float64           // Fracción de registros reservada para la parada temprana (0 para no reservar)
+	Patience           int               // Rondas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	TreeConfig         tree.Config       // Parámetros de cada árbol (profundidad, intervalos, mínimos por hoja; ver tree.Config.ValidateGradient)
+	Seed               int64             // Semilla de la validación, las submuestras y las características candidatas
+	ValidationLoss     float64           // Pérdida logística de validación de los árboles conservados
+}
+
+// Función para crear un modelo de gradient boosting sin entrenar
+func NewGradientBoosting(encoder *encoding.Encoder, numRounds int, maxDepth int, learningRate float64, seed int64) *GradientBoosting {
+	return &GradientBoosting{
+		Encoder:            encoder,
+		NumRounds:          numRounds,
+		LearningRate:       learningRate,
+		Subsample:          0.8,
+		Lambda:             1,
+		ValidationFraction: 0.1,
+		Patience:           10,
+		TreeConfig:         tree.Config{MaxDepth: maxDepth, Histogram: true},
+		Seed:               seed,
+	}
+}
+
+// Función sigmoide
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Función para entrenar el modelo ronda a ronda (implementa classifier.Classifier)
+func (gb *GradientBoosting) Fit(records []preprocess.Record) error {
+	if len(records) == 0 {
+		return classifier.ErrNoRecords
+	}
+	if err := gb.TreeConfig.ValidateGradient(); err != nil {
+		return err
+	}
+	train, validation := preprocess.SplitValidation(records, gb.ValidationFraction, gb.Seed)
+	data, err := tree.Quantize(train, gb.Encoder, gb.TreeConfig.MaxBins)
+	if err != nil {
+		return err
+	}
+
+	// Logit inicial a partir de la proporción de positivos
+	positives := 0
+	for _, label := range data.Labels {
+		positives += int(label)
+	}
+	p := math.Min(math.Max(float64(positives)/float64(len(train)), 1e-6), 1-1e-6)
+	gb.InitialScore = math.Log(p / (1 - p))
+
+	scores := make([]float64, len(train))
+	grad := make([]float64, len(train))
+	hess := make([]float64, len(train))
+	for i := range scores {
+		scores[i] = gb.InitialScore
+	}
+	validationFeatures := make([][]float64, len(validation))
+	validationScores := make([]float64, len(validation))
+	validationLabels := make([]float64, len(validation))
+	for i, record := range validation {
+		validationFeatures[i] = tree.FeatureVector(record, gb.Encoder)
+		validationScores[i] = gb.InitialScore
+		if record.Income == preprocess.PositiveLabel {
+			validationLabels[i] = 1
+		}
+	}
+
+	params := tree.GradientParams{Lambda: gb.Lambda, LearningRate: gb.LearningRate}
+	gb.Trees = nil
+	stopping := metrics.NewEarlyStopping(gb.Patience)
+	for round := 0; round < gb.NumRounds; round++ {
+		// Gradiente y hessiano de la pérdida logística respecto al logit de cada fila
+		for i, score := range scores {
+			p := sigmoid(score)
+			grad[i] = p - float64(data.Labels[i])
+			hess[i] = math.Max(p*(1-p), 1e-16)
+		}
+
+		rng := random.Derive(gb.Seed, round+1) // Cada ronda tiene su propio flujo aleatorio
+		rows := tree.Subsample(len(train), gb.Subsample, rng)
+		dt := tree.BuildGradientTree(data, rows, grad, hess, gb.TreeConfig, params, rng, nil)
+		gb.Trees = append(gb.Trees, dt)
+
+		for i := range scores {
+			scores[i] += dt.BinnedValue(data, i)
+		}
+		if len(validation) == 0 {
+			continue
+		}
+
+		// Parada temprana: conservar los árboles hasta la ronda con menor pérdida de validación
+		for i, features := range validationFeatures {
+			validationScores[i] += dt.PredictValue(features)
+		}
+		loss := 0.0
+		for i, score := range validationScores {
+			loss += metrics.LogLoss(sigmoid(score), validationLabels[i])
+		}
+		if _, stop := stopping.Update(round+1, loss/float64(len(validation))); stop {
+			break
+		}
+	}
+
+	if len(validation) > 0 {
+		gb.Trees = gb.Trees[:stopping.BestEpoch]
+		gb.ValidationLoss = stopping.BestLoss
+	}
+	return nil
+}
+
+// Función para estimar la probabilidad de la clase positiva: sigmoide de la suma de los valores de las hojas
+func (gb *GradientBoosting) PredictProba(record preprocess.Record) float64 {
+	features := tree.FeatureVector(record, gb.Encoder)
+	score := gb.InitialScore
+	for _, dt := range gb.Trees {
+		score += dt.PredictValue(features)
+	}
+	return sigmoid(score)
+}
+
+// Función para realizar predicciones
+func (gb *GradientBoosting) Predict(record preprocess.Record) string {
+	return classifier.LabelFromProba(gb.PredictProba(record))
+}
+
+// Función para predecir las etiquetas de varios registros
+func (gb *GradientBoosting) PredictBatch(records []preprocess.Record) []string {
+	return classifier.BatchPredict(gb.Predict, records, 1)
+}
+
+// Tipo de modelo de boosting en los archivos guardados (el mismo que en la versión concurrente)
+const boostingKind = "gradient-boosting"
+
+// Versión del formato del boosting en disco; guarda árboles del paquete tree, así que también se incrementa
+// cuando cambian ellos
+const boostingVersion = 1
+
+// Guardar el modelo en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func (gb *GradientBoosting) Save(path string) error {
+	return persist.Save(path, boostingKind, boostingVersion, gb)
+}
+
+// Cargar un modelo de boosting guardado con Save
+func LoadGradientBoosting(path string) (*GradientBoosting, error) {
+	gb := &GradientBoosting{}
+	if err := persist.Load(path, boostingKind, boostingVersion, gb); err != nil {
+		return nil, err
+	}
+	return gb, nil
+}
+
+// Función para probar el gradient boosting secuencial (devuelve el modelo entrenado)
+func TestSequentialGradientBoosting(trainData, testData []preprocess.Record, seed int64) *GradientBoosting {
+	fmt.Println("Entrenando Gradient Boosting Secuencial...")
+	encoder := encoding.Fit(trainData, encoding.Ordinal)
+	gb := NewGradientBoosting(encoder, 100, 4, 0.3, seed) // Hasta 100 rondas, profundidad 4, shrinkage 0.3
+
+	start := time.Now()
+	if err := gb.Fit(trainData); err != nil {
+		fmt.Printf("Error al entrenar: %v\n", err)
+		return nil
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("Tiempo de entrenamiento: %s\n", elapsed)
+	fmt.Printf("Rondas conservadas: %d (pérdida de validación %.4f)\n", len(gb.Trees), gb.ValidationLoss)
+
+	// Probar el modelo
+	fmt.Println("Probando Gradient Boosting Secuencial...")
+	accuracy := classifier.Accuracy(gb, testData)
+	fmt.Printf("Precisión: %.2f%%\n", accuracy*100)
+	fmt.Printf("AUC: %.4f\n", classifier.AUC(classifier.ROC(gb, testData)))
+
+	return gb
+}
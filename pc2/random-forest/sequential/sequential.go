@@ -1,206 +1,176 @@
 package sequential
 
 import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/persist"
+	"adult/preprocess"
+	"adult/random"
 	"fmt"
-	"math/rand"
-	"rf/preprocess"
+	"rf/tree"
 	"time"
 )
 
-// Estructura para representar un árbol de decisión (nodo)
-type DecisionTree struct {
-	SplitFeature int
-	Threshold    float64
-	Left         *DecisionTree
-	Right        *DecisionTree
-	Prediction   string
-}
+// El bosque implementa la interfaz común de clasificadores
+var _ classifier.Classifier = (*RandomForest)(nil)
 
 // Estructura del modelo Random Forest
 type RandomForest struct {
-	Trees []*DecisionTree
+	Trees       []*tree.DecisionTree
+	Encoder     *encoding.Encoder // Codificador de características ajustado con los datos de entrenamiento
+	NumTrees    int               // Número de árboles que construye Fit
+	TreeConfig  tree.Config       // Parámetros de construcción de cada árbol (profundidad, criterio, mtry)
+	Seed        int64             // Semilla de la que se deriva el flujo aleatorio de cada árbol
+	OOBAccuracy float64           // Precisión fuera de bolsa calculada al final de Fit
+	TreeWeights []float64         // Peso del voto de cada árbol (nil: todos pesan lo mismo); Fit lo reinicia
+	inBag       [][]bool          // Filas de entrenamiento que entraron en la muestra de cada árbol
 }
 
-// Función para entrenar el modelo Random Forest
-func TrainRandomForest(records []preprocess.Record, numTrees int, maxDepth int) *RandomForest {
-	var forest RandomForest
-	rand.Seed(time.Now().UnixNano())
+// Función para crear un Random Forest sin entrenar
+func NewRandomForest(encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	return &RandomForest{Encoder: encoder, NumTrees: numTrees, TreeConfig: tree.Config{MaxDepth: maxDepth, Criterion: tree.Gini}, Seed: seed}
+}
 
-	for i := 0; i < numTrees; i++ {
-		sample := bootstrapSample(records)
-		tree := buildTree(sample, maxDepth)
-		forest.Trees = append(forest.Trees, tree)
-	}
+// Función para entrenar el modelo Random Forest
+func TrainRandomForest(records []preprocess.Record, encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	forest := NewRandomForest(encoder, numTrees, maxDepth, seed)
+	forest.Fit(records)
+	return forest
+}
 
-	return &forest
+// Función para crear un bosque de árboles extremadamente aleatorios (ExtraTrees) sin entrenar:
+// cortes al azar en cada característica candidata y todas las filas en cada árbol
+func NewExtraTrees(encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	forest := NewRandomForest(encoder, numTrees, maxDepth, seed)
+	forest.TreeConfig.ExtraTrees = true
+	return forest
 }
 
-// Función para realizar predicciones
-func (forest *RandomForest) Predict(record preprocess.Record) string {
-	votes := make(map[string]int)
+// Función para entrenar un bosque ExtraTrees
+func TrainExtraTrees(records []preprocess.Record, encoder *encoding.Encoder, numTrees int, maxDepth int, seed int64) *RandomForest {
+	forest := NewExtraTrees(encoder, numTrees, maxDepth, seed)
+	forest.Fit(records)
+	return forest
+}
 
-	for _, tree := range forest.Trees {
-		prediction := tree.predict(record)
-		votes[prediction]++
+// Función para construir los árboles del bosque con los registros (implementa classifier.Classifier)
+func (forest *RandomForest) Fit(records []preprocess.Record) error {
+	if len(records) == 0 {
+		return classifier.ErrNoRecords
 	}
-
-	// Devolver el voto mayoritario
-	var maxVotes int
-	var majorityLabel string
-	for label, count := range votes {
-		if count > maxVotes {
-			maxVotes = count
-			majorityLabel = label
-		}
+	trainer, err := tree.NewTrainer(records, forest.Encoder, forest.TreeConfig)
+	if err != nil {
+		return err
 	}
+	numTrees, seed := forest.NumTrees, forest.Seed
 
-	return majorityLabel
-}
+	forest.Trees, forest.inBag, forest.TreeWeights = nil, nil, nil
+	for i := 0; i < numTrees; i++ {
+		rng := random.Derive(seed, i) // Cada árbol tiene su propio flujo aleatorio
+		dt, inBag := trainer.BuildTree(rng, nil)
+		forest.Trees = append(forest.Trees, dt)
+		forest.inBag = append(forest.inBag, inBag)
+	}
 
-// Función recursiva para construir un árbol de decisión
-func buildTree(records []preprocess.Record, depth int) *DecisionTree {
-	if depth == 0 || len(records) == 0 {
-		return &DecisionTree{Prediction: majorityLabel(records)}
+	forest.OOBAccuracy = 0 // Sin bootstrap no hay filas fuera de bolsa
+	if forest.TreeConfig.Bootstrap() {
+		// Estimar la generalización con las filas que cada árbol no vio
+		forest.OOBAccuracy = tree.OOBAccuracy(forest.Trees, forest.TreeWeights, forest.inBag, records, forest.Encoder, nil)
 	}
 
-	feature, threshold := chooseBestSplit(records)
-	leftRecords, rightRecords := splitRecords(records, feature, threshold)
+	return nil
+}
 
-	leftChild := buildTree(leftRecords, depth-1)
-	rightChild := buildTree(rightRecords, depth-1)
+// Función para realizar predicciones: clase más probable (el empate va a la clase negativa)
+func (forest *RandomForest) Predict(record preprocess.Record) string {
+	return classifier.LabelFromProba(forest.PredictProba(record))
+}
 
-	return &DecisionTree{
-		SplitFeature: feature,
-		Threshold:    threshold,
-		Left:         leftChild,
-		Right:        rightChild,
-	}
+// Función para estimar la probabilidad de la clase positiva: media (ponderada por TreeWeights)
+// de la distribución de clases de la hoja a la que llega el registro en cada árbol
+func (forest *RandomForest) PredictProba(record preprocess.Record) float64 {
+	features := tree.FeatureVector(record, forest.Encoder)
+	return tree.EnsembleProba(forest.Trees, forest.TreeWeights, features)
 }
 
-// Función para hacer predicciones con un árbol de decisión
-func (tree *DecisionTree) predict(record preprocess.Record) string {
-	if tree.Prediction != "" {
-		return tree.Prediction
+// Función para estimar la probabilidad de la clase positiva de varios registros
+func (forest *RandomForest) PredictProbaBatch(records []preprocess.Record) []float64 {
+	probas := make([]float64, len(records))
+	for i, record := range records {
+		probas[i] = forest.PredictProba(record)
 	}
+	return probas
+}
 
-	// Usar el valor de la característica para determinar el camino en el árbol
-	var featureValue float64
-	switch tree.SplitFeature {
-	case 0:
-		featureValue = float64(record.Age)
-	case 2:
-		featureValue = float64(record.Fnlwgt)
-	case 4:
-		featureValue = float64(record.EducationNum)
-	case 10:
-		featureValue = float64(record.CapitalGain)
-	case 11:
-		featureValue = float64(record.CapitalLoss)
-	case 12:
-		featureValue = float64(record.HoursPerWeek)
-	default:
-		featureValue = 0 // Otras características no numéricas
-	}
+// Función para predecir las etiquetas de varios registros
+func (forest *RandomForest) PredictBatch(records []preprocess.Record) []string {
+	return classifier.BatchPredict(forest.Predict, records, 1)
+}
 
-	if featureValue < tree.Threshold {
-		return tree.Left.predict(record)
-	}
-	return tree.Right.predict(record)
-}
-
-// Elegir el mejor punto de división basado en ganancia de información
-func chooseBestSplit(records []preprocess.Record) (int, float64) {
-	// De forma simplificada, elegimos características aleatorias para simular la elección de división
-	feature := rand.Intn(6)           // Elegimos entre las características numéricas (0: age, 2: fnlwgt, etc.)
-	threshold := rand.Float64() * 100 // Umbral aleatorio
-
-	return feature, threshold
-}
-
-// Dividir los registros en dos subconjuntos según la característica y el umbral
-func splitRecords(records []preprocess.Record, feature int, threshold float64) ([]preprocess.Record, []preprocess.Record) {
-	var left, right []preprocess.Record
-
-	for _, record := range records {
-		var featureValue float64
-		switch feature {
-		case 0:
-			featureValue = float64(record.Age)
-		case 2:
-			featureValue = float64(record.Fnlwgt)
-		case 4:
-			featureValue = float64(record.EducationNum)
-		case 10:
-			featureValue = float64(record.CapitalGain)
-		case 11:
-			featureValue = float64(record.CapitalLoss)
-		case 12:
-			featureValue = float64(record.HoursPerWeek)
-		}
-
-		if featureValue < threshold {
-			left = append(left, record)
-		} else {
-			right = append(right, record)
-		}
+// Fijar el peso del voto de cada árbol (nil vuelve a los pesos iguales)
+func (forest *RandomForest) SetTreeWeights(weights []float64) error {
+	if err := tree.ValidateWeights(weights, len(forest.Trees)); err != nil {
+		return err
 	}
+	forest.TreeWeights = weights
+	return nil
+}
 
-	return left, right
+// Importancia de cada característica (indexada por preprocess.Feature) según la reducción de impureza de sus divisiones
+func (forest *RandomForest) ImpurityImportance() []float64 {
+	return tree.ImpurityImportance(forest.Trees, forest.TreeConfig.Criterion)
 }
 
-// Función de votación para determinar la etiqueta mayoritaria
-func majorityLabel(records []preprocess.Record) string {
-	labelCount := make(map[string]int)
+// Importancia de cada característica según la caída de la precisión sobre los registros al permutarla
+func (forest *RandomForest) PermutationImportance(records []preprocess.Record) []float64 {
+	return tree.PermutationImportance(forest.Trees, forest.TreeWeights, records, forest.Encoder, forest.Seed, nil)
+}
 
-	for _, record := range records {
-		labelCount[record.Income]++
-	}
+// Tipo de modelo en los archivos guardados (el mismo que en la versión concurrente, son intercambiables)
+const modelKind = "random-forest"
 
-	var maxCount int
-	var majorityLabel string
-	for label, count := range labelCount {
-		if count > maxCount {
-			maxCount = count
-			majorityLabel = label
-		}
-	}
+// Versión del formato del bosque en disco (2: los árboles dividen por características de Record con nodos
+// categóricos en lugar de por índices del codificador)
+const modelVersion = 2
 
-	return majorityLabel
+// Guardar el bosque (árboles y codificador) en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func (forest *RandomForest) Save(path string) error {
+	return persist.Save(path, modelKind, modelVersion, forest)
 }
 
-// Bootstrap sample: obtener una muestra aleatoria con reemplazo
-func bootstrapSample(records []preprocess.Record) []preprocess.Record {
-	var sample []preprocess.Record
-	for i := 0; i < len(records); i++ {
-		sample = append(sample, records[rand.Intn(len(records))])
+// Cargar un modelo guardado con Save
+func Load(path string) (*RandomForest, error) {
+	forest := &RandomForest{}
+	if err := persist.Load(path, modelKind, modelVersion, forest); err != nil {
+		return nil, err
 	}
-	return sample
+	return forest, nil
 }
 
-// Función para probar el Random Forest secuencial
-func TestSequentialRandomForest(records []preprocess.Record) {
-	// Dividir datos en entrenamiento y prueba (80% entrenamiento, 20% prueba)
-	numTrain := int(0.8 * float64(len(records)))
-	trainData := records[:numTrain]
-	testData := records[numTrain:]
-
+// Función para probar el Random Forest secuencial con la configuración de árbol dada (devuelve el modelo entrenado)
+func TestSequentialRandomForest(trainData, testData []preprocess.Record, seed int64, config tree.Config) *RandomForest {
 	// Entrenar Random Forest
 	fmt.Println("Entrenando Random Forest Secuencial...")
+	encoder := encoding.Fit(trainData, encoding.Ordinal)      // Los árboles trabajan bien con el índice de la categoría
+	rf := NewRandomForest(encoder, 10, config.MaxDepth, seed) // 10 árboles
+	rf.TreeConfig = config                                    // Modo (exacto o histograma, bagging o ExtraTrees) y profundidad
+
 	start := time.Now()
-	rf := TrainRandomForest(trainData, 10, 5) // 10 árboles, profundidad máxima 5
+	if err := rf.Fit(trainData); err != nil {
+		fmt.Printf("Error al entrenar: %v\n", err)
+		return nil
+	}
 	elapsed := time.Since(start)
 	fmt.Printf("Tiempo de entrenamiento: %s\n", elapsed)
+	if rf.TreeConfig.Bootstrap() {
+		fmt.Printf("Precisión fuera de bolsa: %.2f%%\n", rf.OOBAccuracy*100)
+	}
 
 	// Probar el modelo
 	fmt.Println("Probando Random Forest Secuencial...")
-	correct := 0
-	for _, record := range testData {
-		prediction := rf.Predict(record)
-		if prediction == record.Income {
-			correct++
-		}
-	}
-
-	accuracy := float64(correct) / float64(len(testData))
+	accuracy := classifier.Accuracy(rf, testData)
 	fmt.Printf("Precisión: %.2f%%\n", accuracy*100)
+	fmt.Printf("AUC: %.4f\n", classifier.AUC(classifier.ROC(rf, testData)))
+
+	return rf
 }
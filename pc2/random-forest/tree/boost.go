@@ -0,0 +1,328 @@
+package tree
+
+import (
+	"adult/preprocess"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// Parámetros de un árbol de regresión de gradient boosting (además de los de Config)
+type GradientParams struct {
+	Lambda       float64 // Regularización L2 de los valores de las hojas
+	LearningRate float64 // Shrinkage: factor por el que se multiplica el valor de cada hoja
+}
+
+// Comprobar que la configuración solo usa los parámetros que respetan los árboles de gradient boosting
+// (MaxDepth, MaxBins, MaxFeatures, MinSamplesSplit, MinSamplesLeaf y MinImpurityDecrease; Histogram
+// se ignora porque siempre se usan histogramas): el corte se elige por la ganancia, cada ronda usa su
+// propia submuestra y los árboles no se podan
+func (config Config) ValidateGradient() error {
+	switch {
+	case config.Criterion != Gini:
+		return fmt.Errorf("el boosting elige los cortes por la ganancia, no admite otro criterio que el de por defecto")
+	case config.ExtraTrees:
+		return fmt.Errorf("el boosting no admite el modo ExtraTrees")
+	case config.Sampling != AutoSampling:
+		return fmt.Errorf("el boosting no admite Sampling (la muestra de cada ronda la fija Subsample)")
+	case config.MaxLeafNodes > 0 || config.CCPAlpha > 0:
+		return fmt.Errorf("el boosting no admite la poda por coste-complejidad (MaxLeafNodes, CCPAlpha)")
+	}
+	return nil
+}
+
+// Construir un árbol de regresión sobre los gradientes y hessianos de la pérdida de cada fila, usando
+// histogramas de (gradiente, hessiano) por intervalo de los datos cuantizados. Solo se usan las filas
+// de rows (la submuestra de la ronda), que se reordenan en el sitio. Con MaxFeatures = 0 se evalúan
+// todas las características en cada nodo. La configuración debe haber pasado ValidateGradient
+func BuildGradientTree(data *Binned, rows []int32, grad, hess []float64, config Config, params GradientParams, rng *rand.Rand, pool *Pool) *DecisionTree {
+	g := &gradBuilder{builder: builder{config: config, pool: pool, rootSamples: len(rows)}, data: data, grad: grad, hess: hess, params: params}
+	return g.buildTree(rows, config.MaxDepth, rng, nil)
+}
+
+// Submuestra sin reemplazo de las filas de una ronda: cada fila entra con probabilidad fraction
+// (todas si fraction <= 0 o fraction >= 1)
+func Subsample(n int, fraction float64, rng *rand.Rand) []int32 {
+	rows := make([]int32, 0, n)
+	for i := 0; i < n; i++ {
+		if fraction <= 0 || fraction >= 1 || rng.Float64() < fraction {
+			rows = append(rows, int32(i))
+		}
+	}
+	return rows
+}
+
+// Estado compartido durante la construcción de un árbol de gradient boosting
+type gradBuilder struct {
+	builder
+	data       *Binned
+	grad, hess []float64
+	params     GradientParams
+}
+
+// Suma de gradientes, hessianos y filas de un intervalo (o de un nodo)
+type gradSum struct {
+	G, H float64
+	N    int
+}
+
+// Ganancia (sin el factor 1/2) de una hoja con esas sumas: G² / (H + lambda)
+func (g *gradBuilder) score(s gradSum) float64 {
+	return s.G * s.G / (s.H + g.params.Lambda)
+}
+
+// Función recursiva para construir el árbol de regresión; hists son los histogramas del nodo ya
+// calculados por el padre (nil si no los hay)
+func (g *gradBuilder) buildTree(rows []int32, depth int, rng *rand.Rand, hists [][]gradSum) *DecisionTree {
+	var total gradSum
+	var counts [numClasses]int
+	for _, r := range rows {
+		total.G += g.grad[r]
+		total.H += g.hess[r]
+		counts[g.data.Labels[r]]++
+	}
+	total.N = len(rows)
+	leaf := &DecisionTree{
+		Prediction: labelFor(counts),
+		Counts:     counts,
+		Value:      -total.G / (total.H + g.params.Lambda) * g.params.LearningRate, // Paso de Newton
+	}
+	if g.stop(depth, counts) {
+		return leaf
+	}
+
+	split, hists, ok := g.chooseBestSplit(rows, total, rng, hists)
+	if !ok {
+		return leaf
+	}
+	mid := g.data.partition(rows, split)
+	if mid == 0 || mid == len(rows) {
+		return leaf
+	}
+	node := split.node
+	node.Counts = counts
+
+	// Con todas las características evaluadas basta con recorrer el hijo pequeño: los histogramas
+	// del grande son los del padre menos los del pequeño
+	var leftHists, rightHists [][]gradSum
+	if g.config.MaxFeatures <= 0 && depth > 1 {
+		if mid <= len(rows)-mid {
+			leftHists = g.histograms(rows[:mid])
+			rightHists = subtractHistograms(hists, leftHists)
+		} else {
+			rightHists = g.histograms(rows[mid:])
+			leftHists = subtractHistograms(hists, rightHists)
+		}
+	}
+
+	leftRng := rand.New(rand.NewSource(rng.Int63()))
+	rightRng := rand.New(rand.NewSource(rng.Int63()))
+	buildLeft := func() { node.Left = g.buildTree(rows[:mid], depth-1, leftRng, leftHists) }
+	buildRight := func() { node.Right = g.buildTree(rows[mid:], depth-1, rightRng, rightHists) }
+	if g.parallel(len(rows)) {
+		g.pool.Run(buildLeft, buildRight)
+	} else {
+		buildLeft()
+		buildRight()
+	}
+	return node
+}
+
+// Elegir la división con mayor ganancia entre las características candidatas (histogramas en paralelo
+// si el nodo es grande); devuelve también los histogramas usados, indexados por característica
+func (g *gradBuilder) chooseBestSplit(rows []int32, total gradSum, rng *rand.Rand, hists [][]gradSum) (binSplit, [][]gradSum, bool) {
+	candidates := preprocess.AllFeatures
+	if g.config.MaxFeatures > 0 {
+		candidates = candidateFeatures(len(preprocess.AllFeatures), g.config.MaxFeatures, rng)
+	}
+	if hists == nil {
+		hists = make([][]gradSum, len(preprocess.AllFeatures))
+	}
+	results := make([]binSplit, len(candidates))
+	tasks := make([]func(), len(candidates))
+	for i, feature := range candidates {
+		tasks[i] = func() {
+			if hists[feature] == nil {
+				hists[feature] = g.histogram(rows, feature) // Cada tarea escribe solo su característica
+			}
+			hist := hists[feature]
+			if feature.IsCategorical() {
+				results[i] = g.bestCategorySplit(hist, feature, total)
+			} else {
+				results[i] = g.bestThresholdSplit(hist, feature, total)
+			}
+		}
+	}
+	if g.parallel(len(rows)) {
+		g.pool.Run(tasks...)
+	} else {
+		for _, task := range tasks {
+			task()
+		}
+	}
+
+	// La impureza de binSplit guarda la ganancia con signo cambiado: menor es mejor
+	best := binSplit{}
+	for _, result := range results {
+		if result.ok && result.impurity < 0 && (!best.ok || result.impurity < best.impurity) {
+			best = result
+		}
+	}
+	if best.ok && !g.enoughGain(-best.impurity) {
+		return binSplit{}, hists, false
+	}
+	return best, hists, best.ok
+}
+
+// Histogramas de todas las características (en paralelo si el nodo es grande)
+func (g *gradBuilder) histograms(rows []int32) [][]gradSum {
+	hists := make([][]gradSum, len(preprocess.AllFeatures))
+	tasks := make([]func(), len(hists))
+	for _, feature := range preprocess.AllFeatures {
+		tasks[feature] = func() { hists[feature] = g.histogram(rows, feature) }
+	}
+	if g.parallel(len(rows)) {
+		g.pool.Run(tasks...)
+	} else {
+		for _, task := range tasks {
+			task()
+		}
+	}
+	return hists
+}
+
+// Histogramas del padre menos los de un hijo: los del otro hijo
+func subtractHistograms(parent, child [][]gradSum) [][]gradSum {
+	result := make([][]gradSum, len(parent))
+	for f := range parent {
+		result[f] = make([]gradSum, len(parent[f]))
+		for b := range parent[f] {
+			result[f][b] = gradSum{G: parent[f][b].G - child[f][b].G, H: parent[f][b].H - child[f][b].H, N: parent[f][b].N - child[f][b].N}
+		}
+	}
+	return result
+}
+
+// Histograma de una columna: sumas de gradiente y hessiano de cada intervalo
+func (g *gradBuilder) histogram(rows []int32, feature preprocess.Feature) []gradSum {
+	hist := make([]gradSum, g.data.NumBins[feature])
+	column := g.data.Columns[feature]
+	for _, r := range rows {
+		bin := &hist[column[r]]
+		bin.G += g.grad[r]
+		bin.H += g.hess[r]
+		bin.N++
+	}
+	return hist
+}
+
+// Ganancia de dividir el nodo en left y el resto; ok es false si algún hijo tiene menos de MinSamplesLeaf filas
+func (g *gradBuilder) gain(left, total gradSum) (float64, bool) {
+	right := gradSum{G: total.G - left.G, H: total.H - left.H, N: total.N - left.N}
+	if left.N < g.minLeaf() || right.N < g.minLeaf() {
+		return 0, false
+	}
+	return (g.score(left) + g.score(right) - g.score(total)) / 2, true
+}
+
+// Indica si la ganancia de la mejor división alcanza MinImpurityDecrease: la ganancia ya suma sobre las
+// filas del nodo, así que dividirla por las de la raíz la pondera igual que enoughDecrease
+func (g *gradBuilder) enoughGain(gain float64) bool {
+	return gain/float64(g.rootSamples) >= g.config.MinImpurityDecrease
+}
+
+// Mejor corte por umbral recorriendo los intervalos en orden (mismo umbral que bestThresholdBinSplit)
+func (g *gradBuilder) bestThresholdSplit(hist []gradSum, feature preprocess.Feature, total gradSum) binSplit {
+	var left gradSum
+	best := binSplit{}
+	bestGain, bestBin := 0.0, 0
+	for b := 0; b < len(hist)-1; b++ {
+		left.G += hist[b].G
+		left.H += hist[b].H
+		left.N += hist[b].N
+		gain, ok := g.gain(left, total)
+		if ok && (!best.ok || gain > bestGain) {
+			bestGain, bestBin, best.ok = gain, b, true
+		}
+	}
+	if !best.ok {
+		return best
+	}
+
+	best.node = &DecisionTree{SplitFeature: feature, Threshold: g.data.Edges[feature][bestBin], splitBin: bestBin}
+	best.impurity = -bestGain
+	best.goesLeft = make([]bool, len(hist))
+	for b := 0; b <= bestBin; b++ {
+		best.goesLeft[b] = true
+	}
+	return best
+}
+
+// Mejor partición de las categorías: se ordenan por G/(H + lambda) y se prueban los prefijos
+func (g *gradBuilder) bestCategorySplit(hist []gradSum, feature preprocess.Feature, total gradSum) binSplit {
+	var present []int
+	for code, s := range hist {
+		if s.N > 0 {
+			present = append(present, code)
+		}
+	}
+	if len(present) < 2 {
+		return binSplit{}
+	}
+	ratio := func(code int) float64 {
+		return hist[code].G / (hist[code].H + g.params.Lambda)
+	}
+	sort.SliceStable(present, func(a, b int) bool { return ratio(present[a]) < ratio(present[b]) })
+
+	var left gradSum
+	bestPrefix, bestGain := 0, 0.0
+	for i := 0; i < len(present)-1; i++ {
+		left.G += hist[present[i]].G
+		left.H += hist[present[i]].H
+		left.N += hist[present[i]].N
+		gain, ok := g.gain(left, total)
+		if ok && (bestPrefix == 0 || gain > bestGain) {
+			bestPrefix, bestGain = i+1, gain
+		}
+	}
+	if bestPrefix == 0 {
+		return binSplit{}
+	}
+
+	leftCodes := slices.Clone(present[:bestPrefix])
+	slices.Sort(leftCodes)
+	goesLeft := make([]bool, len(hist))
+	for _, code := range leftCodes {
+		goesLeft[code] = true
+	}
+	return binSplit{node: &DecisionTree{SplitFeature: feature, Categories: leftCodes}, impurity: -bestGain, goesLeft: goesLeft, ok: true}
+}
+
+// Valor de la hoja a la que llega un vector de características
+func (tree *DecisionTree) PredictValue(features []float64) float64 {
+	if tree.Prediction != "" {
+		return tree.Value
+	}
+	if tree.goesLeft(features) {
+		return tree.Left.PredictValue(features)
+	}
+	return tree.Right.PredictValue(features)
+}
+
+// Valor de la hoja a la que llega una fila de los datos cuantizados con los que se construyó el árbol
+func (tree *DecisionTree) BinnedValue(data *Binned, row int) float64 {
+	for tree.Prediction == "" {
+		bin := int(data.Columns[tree.SplitFeature][row])
+		left := bin <= tree.splitBin
+		if tree.SplitFeature.IsCategorical() {
+			left = slices.Contains(tree.Categories, bin)
+		}
+		if left {
+			tree = tree.Left
+		} else {
+			tree = tree.Right
+		}
+	}
+	return tree.Value
+}
@@ -0,0 +1,87 @@
+package tree
+
+import (
+	"adult/encoding"
+	"adult/preprocess"
+	"adult/random"
+	"testing"
+)
+
+// Cargar los primeros registros del conjunto de entrenamiento y cuantizarlos
+func loadBinned(tb testing.TB, n int) ([]preprocess.Record, *encoding.Encoder, *Binned) {
+	tb.Helper()
+	records, err := preprocess.ReadRecords("../adult.data", n)
+	if err != nil {
+		tb.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.Ordinal)
+	data, err := Quantize(records, encoder, 0)
+	if err != nil {
+		tb.Fatalf("Quantize: %v", err)
+	}
+	return records, encoder, data
+}
+
+// Número de hojas de un árbol
+func countLeaves(tree *DecisionTree) int {
+	if tree.Prediction != "" {
+		return 1
+	}
+	return countLeaves(tree.Left) + countLeaves(tree.Right)
+}
+
+// MinImpurityDecrease debe limitar también los árboles de gradient boosting: cuanto mayor, menos hojas
+func TestGradientTreeMinImpurityDecrease(t *testing.T) {
+	_, _, data := loadBinned(t, 4000)
+	// Gradientes y hessianos de la pérdida logística partiendo de p = 0.5 en todas las filas
+	grad := make([]float64, data.NumRows())
+	hess := make([]float64, data.NumRows())
+	for i, label := range data.Labels {
+		grad[i], hess[i] = 0.5-float64(label), 0.25
+	}
+	params := GradientParams{Lambda: 1, LearningRate: 0.1}
+
+	tests := []struct {
+		name        string
+		minDecrease float64
+		maxLeaves   int // Máximo de hojas esperado (0 sin límite)
+		minLeaves   int // Mínimo de hojas esperado
+	}{
+		{"sin restricción", 0, 0, 16},
+		{"moderada", 0.005, 15, 2},
+		{"imposible", 1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := Subsample(data.NumRows(), 1, nil)
+			config := Config{MaxDepth: 6, MinImpurityDecrease: tt.minDecrease}
+			leaves := countLeaves(BuildGradientTree(data, rows, grad, hess, config, params, random.New(42), nil))
+			if leaves < tt.minLeaves || (tt.maxLeaves > 0 && leaves > tt.maxLeaves) {
+				t.Errorf("%d hojas, se esperaban entre %d y %d", leaves, tt.minLeaves, tt.maxLeaves)
+			}
+		})
+	}
+}
+
+// Los parámetros que los árboles de gradient boosting no aplican deben rechazarse en lugar de ignorarse
+func TestValidateGradient(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"admitidos", Config{MaxDepth: 6, MaxBins: 64, MaxFeatures: 4, MinSamplesLeaf: 5, MinImpurityDecrease: 0.01}, true},
+		{"criterio", Config{MaxDepth: 6, Criterion: Entropy}, false},
+		{"extratrees", Config{MaxDepth: 6, ExtraTrees: true}, false},
+		{"muestreo", Config{MaxDepth: 6, Sampling: BootstrapSampling}, false},
+		{"máximo de hojas", Config{MaxDepth: 6, MaxLeafNodes: 8}, false},
+		{"poda", Config{MaxDepth: 6, CCPAlpha: 0.01}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.ValidateGradient(); (err == nil) != tt.valid {
+				t.Errorf("error %v, se esperaba válida = %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"fmt"
+	"math"
+)
+
+// Probabilidad de la clase positiva de un conjunto de árboles: media de las distribuciones de clases
+// de las hojas, ponderada por weights (nil para que todos los árboles pesen lo mismo)
+func EnsembleProba(trees []*DecisionTree, weights []float64, features []float64) float64 {
+	sum, totalWeight := 0.0, 0.0
+	for t, tree := range trees {
+		w := treeWeight(weights, t)
+		sum += w * tree.PredictProba(features)
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return sum / totalWeight
+}
+
+// Media ponderada de las probabilidades ya calculadas de cada árbol (mismo resultado que EnsembleProba)
+func AverageProba(probas []float64, weights []float64) float64 {
+	sum, totalWeight := 0.0, 0.0
+	for t, p := range probas {
+		w := treeWeight(weights, t)
+		sum += w * p
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return sum / totalWeight
+}
+
+// Peso del árbol t (1 si no hay pesos)
+func treeWeight(weights []float64, t int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[t]
+}
+
+// Comprobar que hay un peso finito y no negativo por árbol y que no todos son cero (nil es válido: pesos iguales)
+func ValidateWeights(weights []float64, numTrees int) error {
+	if weights == nil {
+		return nil
+	}
+	if len(weights) != numTrees {
+		return fmt.Errorf("se esperaban %d pesos (uno por árbol), hay %d", numTrees, len(weights))
+	}
+	total := 0.0
+	for t, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("peso %v no válido para el árbol %d", w, t)
+		}
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("todos los pesos de los árboles son cero")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package tree
+
+import (
+	"adult/encoding"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Escribir el árbol en formato Graphviz DOT: cada nodo muestra su regla de división (o la clase
+// predicha en las hojas), los ejemplos que llegaron a él y su distribución de clases. El codificador
+// traduce los códigos de las categorías a sus nombres (nil para mostrar los códigos)
+func (tree *DecisionTree) WriteDOT(w io.Writer, encoder *encoding.Encoder) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, "digraph arbol {")
+	fmt.Fprintln(writer, "  node [shape=box, fontname=\"Helvetica\"];")
+	nextID := 0
+	tree.writeDOTNode(writer, encoder, &nextID)
+	fmt.Fprintln(writer, "}")
+	return writer.Flush()
+}
+
+// Escribir un nodo y su subárbol; devuelve el identificador asignado al nodo
+func (tree *DecisionTree) writeDOTNode(w io.Writer, encoder *encoding.Encoder, nextID *int) int {
+	id := *nextID
+	*nextID++
+
+	var lines []string
+	if tree.Prediction != "" {
+		lines = append(lines, "clase = "+tree.Prediction)
+		if tree.Value != 0 {
+			lines = append(lines, "valor = "+strconv.FormatFloat(tree.Value, 'g', 4, 64))
+		}
+	} else {
+		lines = append(lines, tree.rule(encoder))
+	}
+	lines = append(lines,
+		fmt.Sprintf("muestras = %d", tree.Counts[0]+tree.Counts[1]),
+		fmt.Sprintf("clases = [%d, %d]", tree.Counts[0], tree.Counts[1]))
+	fmt.Fprintf(w, "  n%d [label=%s];\n", id, strconv.Quote(strings.Join(lines, "\n")))
+
+	if tree.Prediction == "" {
+		leftID := tree.Left.writeDOTNode(w, encoder, nextID)
+		rightID := tree.Right.writeDOTNode(w, encoder, nextID)
+		fmt.Fprintf(w, "  n%d -> n%d [label=\"sí\"];\n", id, leftID)
+		fmt.Fprintf(w, "  n%d -> n%d [label=\"no\"];\n", id, rightID)
+	}
+	return id
+}
+
+// Escribir el árbol como reglas if/else anidadas; cada hoja indica la clase y la distribución de
+// clases de los ejemplos que llegaron a ella
+func (tree *DecisionTree) WriteRules(w io.Writer, encoder *encoding.Encoder) error {
+	writer := bufio.NewWriter(w)
+	tree.writeRules(writer, encoder, 0)
+	return writer.Flush()
+}
+
+// Escribir las reglas del subárbol con la sangría de su profundidad
+func (tree *DecisionTree) writeRules(w io.Writer, encoder *encoding.Encoder, depth int) {
+	indent := strings.Repeat("    ", depth)
+	if tree.Prediction != "" {
+		fmt.Fprintf(w, "%sreturn %q // muestras: %d %v", indent, tree.Prediction, tree.Counts[0]+tree.Counts[1], tree.Counts)
+		if tree.Value != 0 {
+			fmt.Fprintf(w, ", valor: %.4g", tree.Value)
+		}
+		fmt.Fprintln(w)
+		return
+	}
+	fmt.Fprintf(w, "%sif %s {\n", indent, tree.rule(encoder))
+	tree.Left.writeRules(w, encoder, depth+1)
+	fmt.Fprintf(w, "%s} else {\n", indent)
+	tree.Right.writeRules(w, encoder, depth+1)
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+// Regla de división de un nodo interno en texto (condición para ir a la rama izquierda)
+func (tree *DecisionTree) rule(encoder *encoding.Encoder) string {
+	if !tree.SplitFeature.IsCategorical() {
+		return fmt.Sprintf("%s < %s", tree.SplitFeature, strconv.FormatFloat(tree.Threshold, 'g', -1, 64))
+	}
+	names := make([]string, len(tree.Categories))
+	for i, code := range tree.Categories {
+		names[i] = strconv.Itoa(code)
+		if encoder != nil {
+			if categories := encoder.CategoriesOf(tree.SplitFeature); code < len(categories) {
+				names[i] = categories[code]
+			}
+		}
+	}
+	return fmt.Sprintf("%s in {%s}", tree.SplitFeature, strings.Join(names, ", "))
+}
@@ -0,0 +1,119 @@
+package tree
+
+import (
+	"adult/preprocess"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// División al azar de una característica (ExtraTrees): en las numéricas un umbral uniforme entre el mínimo
+// y el máximo de la columna en el nodo; en las categóricas un subconjunto al azar de las categorías presentes.
+// Devuelve la impureza ponderada de la división para compararla con las de las demás candidatas
+func randomSplit(examples []Example, feature preprocess.Feature, parentCounts [numClasses]int, criterion Criterion, minLeaf int, rng *rand.Rand) (*DecisionTree, float64, bool) {
+	var node *DecisionTree
+	if feature.IsCategorical() {
+		var seen []bool
+		for _, ex := range examples {
+			code := int(ex.Features[feature])
+			for code >= len(seen) {
+				seen = append(seen, false)
+			}
+			seen[code] = true
+		}
+		var present []int // Categorías presentes en orden de código, antes de barajar
+		for code, ok := range seen {
+			if ok {
+				present = append(present, code)
+			}
+		}
+		left, ok := randomCategories(present, rng)
+		if !ok {
+			return nil, 0, false
+		}
+		node = &DecisionTree{SplitFeature: feature, Categories: left}
+	} else {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, ex := range examples {
+			lo = min(lo, ex.Features[feature])
+			hi = max(hi, ex.Features[feature])
+		}
+		threshold := lo + rng.Float64()*(hi-lo)
+		if !(threshold > lo) {
+			return nil, 0, false // Columna constante en el nodo (o umbral en el mínimo): la izquierda quedaría vacía
+		}
+		node = &DecisionTree{SplitFeature: feature, Threshold: threshold}
+	}
+
+	var leftCounts, rightCounts [numClasses]int
+	for _, ex := range examples {
+		if node.goesLeft(ex.Features) {
+			leftCounts[ex.Label]++
+		}
+	}
+	for c := range rightCounts {
+		rightCounts[c] = parentCounts[c] - leftCounts[c]
+	}
+	if leftCounts[0]+leftCounts[1] < minLeaf || rightCounts[0]+rightCounts[1] < minLeaf {
+		return nil, 0, false
+	}
+	return node, weightedImpurity(leftCounts, rightCounts, criterion), true
+}
+
+// División al azar en el modo histograma: en las numéricas el corte se sortea entre los intervalos
+// ocupados del nodo; en las categóricas, un subconjunto al azar de las categorías presentes
+func randomBinSplit(hist [][numClasses]int, feature preprocess.Feature, edges []float64, parentCounts [numClasses]int, criterion Criterion, minLeaf int, rng *rand.Rand) binSplit {
+	var present []int
+	for bin, c := range hist {
+		if c[0]+c[1] > 0 {
+			present = append(present, bin)
+		}
+	}
+	if len(present) < 2 {
+		return binSplit{}
+	}
+
+	var node *DecisionTree
+	goesLeft := make([]bool, len(hist))
+	if feature.IsCategorical() {
+		left, _ := randomCategories(present, rng)
+		for _, code := range left {
+			goesLeft[code] = true
+		}
+		node = &DecisionTree{SplitFeature: feature, Categories: left}
+	} else {
+		first, last := present[0], present[len(present)-1]
+		cut := first + rng.Intn(last-first) // Último intervalo de la rama izquierda
+		for b := 0; b <= cut; b++ {
+			goesLeft[b] = true
+		}
+		node = &DecisionTree{SplitFeature: feature, Threshold: edges[cut], splitBin: cut}
+	}
+
+	var leftCounts, rightCounts [numClasses]int
+	for bin, c := range hist {
+		if goesLeft[bin] {
+			leftCounts[0] += c[0]
+			leftCounts[1] += c[1]
+		}
+	}
+	for c := range rightCounts {
+		rightCounts[c] = parentCounts[c] - leftCounts[c]
+	}
+	if leftCounts[0]+leftCounts[1] < minLeaf || rightCounts[0]+rightCounts[1] < minLeaf {
+		return binSplit{}
+	}
+	return binSplit{node: node, impurity: weightedImpurity(leftCounts, rightCounts, criterion), goesLeft: goesLeft, ok: true}
+}
+
+// Subconjunto al azar, no vacío y distinto del total, de las categorías presentes (ordenado)
+func randomCategories(present []int, rng *rand.Rand) ([]int, bool) {
+	if len(present) < 2 {
+		return nil, false
+	}
+	shuffled := slices.Clone(present)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	left := shuffled[:1+rng.Intn(len(shuffled)-1)]
+	slices.Sort(left)
+	return left, true
+}
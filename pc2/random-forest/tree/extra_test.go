@@ -0,0 +1,85 @@
+package tree
+
+import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/preprocess"
+	"adult/random"
+	"slices"
+	"testing"
+)
+
+// Modos de construcción del bosque que se comparan: bagging clásico frente a ExtraTrees, en exacto y con histogramas
+var forestModes = []struct {
+	name   string
+	config Config
+}{
+	{"bagging/exacto", Config{MaxDepth: 8, Criterion: Gini}},
+	{"bagging/histograma", Config{MaxDepth: 8, Criterion: Gini, Histogram: true}},
+	{"extratrees/exacto", Config{MaxDepth: 8, Criterion: Gini, ExtraTrees: true}},
+	{"extratrees/histograma", Config{MaxDepth: 8, Criterion: Gini, Histogram: true, ExtraTrees: true}},
+}
+
+// Construir numTrees árboles con el flujo aleatorio de cada árbol del bosque
+func buildForest(tb testing.TB, trainer *Trainer, numTrees int) ([]*DecisionTree, [][]bool) {
+	tb.Helper()
+	trees := make([]*DecisionTree, numTrees)
+	inBag := make([][]bool, numTrees)
+	for i := range trees {
+		trees[i], inBag[i] = trainer.BuildTree(random.Derive(42, i), nil)
+	}
+	return trees, inBag
+}
+
+// Precisión del bosque sobre los registros
+func forestAccuracy(trees []*DecisionTree, records []preprocess.Record, encoder *encoding.Encoder) float64 {
+	correct := 0
+	for _, record := range records {
+		proba := EnsembleProba(trees, nil, FeatureVector(record, encoder))
+		if classifier.LabelFromProba(proba) == record.Income {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(records))
+}
+
+// ExtraTrees usa todas las filas en cada árbol y el bagging una muestra bootstrap; los dos deben
+// generalizar a registros no vistos
+func TestExtraTreesVsBagging(t *testing.T) {
+	records, encoder, _ := loadBinned(t, 6000)
+	train, test := records[:4000], records[4000:]
+
+	for _, mode := range forestModes {
+		t.Run(mode.name, func(t *testing.T) {
+			trainer, err := NewTrainer(train, encoder, mode.config)
+			if err != nil {
+				t.Fatalf("NewTrainer: %v", err)
+			}
+			trees, inBag := buildForest(t, trainer, 10)
+			for i, rows := range inBag {
+				if all := !slices.Contains(rows, false); all == mode.config.Bootstrap() {
+					t.Errorf("árbol %d: todas las filas en la muestra = %v con Bootstrap() = %v", i, all, mode.config.Bootstrap())
+				}
+			}
+			if accuracy := forestAccuracy(trees, test, encoder); accuracy < 0.8 {
+				t.Errorf("precisión %.4f, se esperaba al menos 0.8", accuracy)
+			}
+		})
+	}
+}
+
+// Tiempo de construcción de un bosque de 10 árboles en cada modo (ExtraTrees no busca el mejor corte)
+func BenchmarkForestModes(b *testing.B) {
+	records, encoder, _ := loadBinned(b, 20000)
+	for _, mode := range forestModes {
+		trainer, err := NewTrainer(records, encoder, mode.config)
+		if err != nil {
+			b.Fatalf("NewTrainer: %v", err)
+		}
+		b.Run(mode.name, func(b *testing.B) {
+			for range b.N {
+				buildForest(b, trainer, 10)
+			}
+		})
+	}
+}
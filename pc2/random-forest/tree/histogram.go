@@ -0,0 +1,355 @@
+package tree
+
+import (
+	"adult/encoding"
+	"adult/preprocess"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// Número máximo de intervalos por columna (cada valor cuantizado ocupa un byte)
+const maxBins = 256
+
+// Filas usadas como máximo para calcular los cortes de una columna numérica
+const quantileSample = 200000
+
+// Datos de entrenamiento cuantizados una sola vez para todos los árboles: una columna compacta
+// de intervalos por característica (indexada por preprocess.Feature) y la clase de cada fila
+type Binned struct {
+	Columns [][]uint8   // Intervalo de cada fila; en las categóricas es el código de la categoría
+	Edges   [][]float64 // Cortes de las columnas numéricas: el intervalo b contiene Edges[b-1] <= v < Edges[b]
+	NumBins []int       // Número de intervalos de cada columna
+	Labels  []uint8     // Clase de cada fila (1 para >50K)
+}
+
+// Cuantizar los registros: las columnas numéricas se dividen en a lo sumo bins intervalos por
+// cuantiles y las categóricas usan directamente el código del codificador
+func Quantize(records []preprocess.Record, encoder *encoding.Encoder, bins int) (*Binned, error) {
+	if bins <= 0 || bins > maxBins {
+		bins = maxBins
+	}
+	n := len(records)
+	numFeatures := len(preprocess.AllFeatures)
+	data := &Binned{
+		Columns: make([][]uint8, numFeatures),
+		Edges:   make([][]float64, numFeatures),
+		NumBins: make([]int, numFeatures),
+		Labels:  make([]uint8, n),
+	}
+	for i, record := range records {
+		if record.Income == preprocess.PositiveLabel {
+			data.Labels[i] = 1
+		}
+	}
+
+	values := make([]float64, n)
+	for _, feature := range preprocess.AllFeatures {
+		column := make([]uint8, n)
+		if feature.IsCategorical() {
+			data.NumBins[feature] = len(encoder.CategoriesOf(feature))
+			if data.NumBins[feature] > maxBins {
+				return nil, fmt.Errorf("la columna %s tiene %d categorías (máximo %d)", feature, data.NumBins[feature], maxBins)
+			}
+			for i, record := range records {
+				column[i] = uint8(encoder.Code(feature, record.Category(feature)))
+			}
+		} else {
+			for i, record := range records {
+				values[i] = record.Numeric(feature)
+			}
+			edges := quantileEdges(values, bins)
+			data.Edges[feature] = edges
+			data.NumBins[feature] = len(edges) + 1
+			for i, v := range values {
+				column[i] = uint8(binOf(edges, v))
+			}
+		}
+		data.Columns[feature] = column
+	}
+	return data, nil
+}
+
+// Número de filas de los datos cuantizados
+func (data *Binned) NumRows() int {
+	return len(data.Labels)
+}
+
+// Cortes de una columna numérica: puntos medios entre valores distintos consecutivos, tomando
+// uno por cuantil cuando hay más valores distintos que intervalos
+func quantileEdges(values []float64, bins int) []float64 {
+	sorted := values
+	if len(values) > quantileSample {
+		// Muestra con paso fraccionario fijo: reproducible y repartida por toda la columna
+		sorted = make([]float64, quantileSample)
+		for i := range sorted {
+			sorted[i] = values[i*len(values)/quantileSample]
+		}
+	} else {
+		sorted = slices.Clone(values)
+	}
+	slices.Sort(sorted)
+	distinct := slices.Compact(slices.Clone(sorted))
+
+	var edges []float64
+	if len(distinct) <= bins {
+		for i := 1; i < len(distinct); i++ {
+			edges = append(edges, (distinct[i-1]+distinct[i])/2)
+		}
+		return edges
+	}
+	for k := 1; k < bins; k++ {
+		v := sorted[k*len(sorted)/bins]
+		j := sort.SearchFloat64s(distinct, v) // Posición de v entre los valores distintos
+		if j == 0 {
+			continue
+		}
+		edge := (distinct[j-1] + v) / 2
+		if len(edges) == 0 || edge > edges[len(edges)-1] {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// Intervalo de un valor: número de cortes menores o iguales que él
+func binOf(edges []float64, v float64) int {
+	return sort.Search(len(edges), func(i int) bool { return v < edges[i] })
+}
+
+// Construir un árbol a partir de los histogramas de los datos cuantizados; weights indica cuántas
+// veces cuenta cada fila (nil para usar todas una vez)
+func BuildHistogram(data *Binned, weights []int32, config Config, rng *rand.Rand) *DecisionTree {
+	return BuildHistogramConcurrent(data, weights, config, rng, nil)
+}
+
+// Versión de BuildHistogram que reparte el trabajo en el pool; con la misma semilla produce el mismo árbol
+func BuildHistogramConcurrent(data *Binned, weights []int32, config Config, rng *rand.Rand, pool *Pool) *DecisionTree {
+	if weights == nil {
+		weights = make([]int32, data.NumRows())
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	// Solo se recorren las filas presentes en la muestra; los nodos se quedan con tramos de este arreglo
+	rows := make([]int32, 0, len(weights))
+	for i, w := range weights {
+		if w > 0 {
+			rows = append(rows, int32(i))
+		}
+	}
+	rootSamples := 0
+	for _, w := range weights {
+		rootSamples += int(w)
+	}
+	h := &histBuilder{builder: builder{config: config, pool: pool, rootSamples: rootSamples}, data: data, weights: weights}
+	return h.finish(h.buildTree(rows, config.MaxDepth, rng))
+}
+
+// Estado compartido durante la construcción de un árbol con histogramas
+type histBuilder struct {
+	builder
+	data    *Binned
+	weights []int32
+}
+
+// División elegida en el espacio de intervalos: goesLeft indica a qué rama va cada intervalo
+type binSplit struct {
+	node     *DecisionTree
+	impurity float64
+	goesLeft []bool
+	ok       bool
+}
+
+// Función recursiva para construir el árbol; rows es el tramo de filas del nodo y se reordena en el sitio
+func (h *histBuilder) buildTree(rows []int32, depth int, rng *rand.Rand) *DecisionTree {
+	counts := h.classCounts(rows)
+	if h.stop(depth, counts) {
+		return &DecisionTree{Prediction: labelFor(counts), Counts: counts}
+	}
+
+	split, ok := h.chooseBestSplit(rows, counts, rng)
+	if !ok {
+		return &DecisionTree{Prediction: labelFor(counts), Counts: counts}
+	}
+	mid := h.data.partition(rows, split)
+	if mid == 0 || mid == len(rows) {
+		// Una partición vacía dejaría un hijo sin filas: el nodo se queda como hoja
+		return &DecisionTree{Prediction: labelFor(counts), Counts: counts}
+	}
+	node := split.node
+	node.Counts = counts
+
+	// Igual que en Build: los generadores de los hijos se derivan antes de construirlos
+	leftRng := rand.New(rand.NewSource(rng.Int63()))
+	rightRng := rand.New(rand.NewSource(rng.Int63()))
+	buildLeft := func() { node.Left = h.buildTree(rows[:mid], depth-1, leftRng) }
+	buildRight := func() { node.Right = h.buildTree(rows[mid:], depth-1, rightRng) }
+	if h.parallel(len(rows)) {
+		h.pool.Run(buildLeft, buildRight)
+	} else {
+		buildLeft()
+		buildRight()
+	}
+	return node
+}
+
+// Contar las filas de cada clase teniendo en cuenta su peso
+func (h *histBuilder) classCounts(rows []int32) [numClasses]int {
+	var counts [numClasses]int
+	for _, r := range rows {
+		counts[h.data.Labels[r]] += int(h.weights[r])
+	}
+	return counts
+}
+
+// Elegir la mejor división entre las características candidatas a partir de sus histogramas
+func (h *histBuilder) chooseBestSplit(rows []int32, parentCounts [numClasses]int, rng *rand.Rand) (binSplit, bool) {
+	criterion, minLeaf := h.config.Criterion, h.minLeaf()
+	parentImpurity := impurity(parentCounts, parentCounts[0]+parentCounts[1], criterion)
+	if parentImpurity == 0 {
+		return binSplit{}, false
+	}
+
+	candidates := candidateFeatures(len(preprocess.AllFeatures), h.config.MaxFeatures, rng)
+	rngs := h.candidateRngs(len(candidates), rng)
+	results := make([]binSplit, len(candidates))
+	tasks := make([]func(), len(candidates))
+	for i, feature := range candidates {
+		tasks[i] = func() {
+			hist := h.histogram(rows, feature)
+			if h.config.ExtraTrees {
+				results[i] = randomBinSplit(hist, feature, h.data.Edges[feature], parentCounts, criterion, minLeaf, rngs[i])
+			} else if feature.IsCategorical() {
+				results[i] = bestCategoryBinSplit(hist, feature, parentCounts, criterion, minLeaf)
+			} else {
+				results[i] = bestThresholdBinSplit(hist, feature, h.data.Edges[feature], parentCounts, criterion, minLeaf)
+			}
+		}
+	}
+	if h.parallel(len(rows)) {
+		h.pool.Run(tasks...)
+	} else {
+		for _, task := range tasks {
+			task()
+		}
+	}
+
+	best := binSplit{impurity: parentImpurity}
+	for _, result := range results {
+		if result.ok && result.impurity < best.impurity {
+			best = result
+		}
+	}
+	if !best.ok || !h.enoughDecrease(parentCounts, parentImpurity, best.impurity) {
+		return binSplit{}, false
+	}
+	return best, true
+}
+
+// Histograma de una columna: peso de cada clase en cada intervalo
+func (h *histBuilder) histogram(rows []int32, feature preprocess.Feature) [][numClasses]int {
+	hist := make([][numClasses]int, h.data.NumBins[feature])
+	column := h.data.Columns[feature]
+	for _, r := range rows {
+		hist[column[r]][h.data.Labels[r]] += int(h.weights[r])
+	}
+	return hist
+}
+
+// Recorrer los intervalos en orden y cortar tras el que deja la menor impureza ponderada; el umbral
+// es el corte superior del intervalo, así el árbol predice con los valores sin cuantizar
+func bestThresholdBinSplit(hist [][numClasses]int, feature preprocess.Feature, edges []float64, parentCounts [numClasses]int, criterion Criterion, minLeaf int) binSplit {
+	var leftCounts [numClasses]int
+	best := binSplit{}
+	bestImpurity, bestBin := 0.0, 0
+	for b := 0; b < len(hist)-1; b++ {
+		for c := range leftCounts {
+			leftCounts[c] += hist[b][c]
+		}
+		var rightCounts [numClasses]int
+		for c := range rightCounts {
+			rightCounts[c] = parentCounts[c] - leftCounts[c]
+		}
+		if leftCounts[0]+leftCounts[1] < minLeaf || rightCounts[0]+rightCounts[1] < minLeaf {
+			continue // Las dos ramas deben tener al menos MinSamplesLeaf filas
+		}
+		weighted := weightedImpurity(leftCounts, rightCounts, criterion)
+		if !best.ok || weighted < bestImpurity {
+			bestImpurity, bestBin, best.ok = weighted, b, true
+		}
+	}
+	if !best.ok {
+		return best
+	}
+
+	best.node = &DecisionTree{SplitFeature: feature, Threshold: edges[bestBin], splitBin: bestBin}
+	best.impurity = bestImpurity
+	best.goesLeft = make([]bool, len(hist))
+	for b := 0; b <= bestBin; b++ {
+		best.goesLeft[b] = true
+	}
+	return best
+}
+
+// Mejor partición de las categorías a partir del histograma (mismo orden por proporción de positivos que bestCategorySplit)
+func bestCategoryBinSplit(hist [][numClasses]int, feature preprocess.Feature, parentCounts [numClasses]int, criterion Criterion, minLeaf int) binSplit {
+	var present []int
+	for code, c := range hist {
+		if c[0]+c[1] > 0 {
+			present = append(present, code)
+		}
+	}
+	if len(present) < 2 {
+		return binSplit{}
+	}
+	positiveRate := func(code int) float64 {
+		return float64(hist[code][1]) / float64(hist[code][0]+hist[code][1])
+	}
+	sort.SliceStable(present, func(a, b int) bool { return positiveRate(present[a]) < positiveRate(present[b]) })
+
+	var leftCounts [numClasses]int
+	bestPrefix, bestImpurity := 0, 0.0
+	for i := 0; i < len(present)-1; i++ {
+		for c := range leftCounts {
+			leftCounts[c] += hist[present[i]][c]
+		}
+		var rightCounts [numClasses]int
+		for c := range rightCounts {
+			rightCounts[c] = parentCounts[c] - leftCounts[c]
+		}
+		if leftCounts[0]+leftCounts[1] < minLeaf || rightCounts[0]+rightCounts[1] < minLeaf {
+			continue
+		}
+		weighted := weightedImpurity(leftCounts, rightCounts, criterion)
+		if bestPrefix == 0 || weighted < bestImpurity {
+			bestPrefix, bestImpurity = i+1, weighted
+		}
+	}
+
+	if bestPrefix == 0 {
+		return binSplit{}
+	}
+
+	left := slices.Clone(present[:bestPrefix])
+	slices.Sort(left)
+	goesLeft := make([]bool, len(hist))
+	for _, code := range left {
+		goesLeft[code] = true
+	}
+	return binSplit{node: &DecisionTree{SplitFeature: feature, Categories: left}, impurity: bestImpurity, goesLeft: goesLeft, ok: true}
+}
+
+// Reordenar las filas del nodo en el sitio: primero las de la rama izquierda; devuelve cuántas son
+func (data *Binned) partition(rows []int32, split binSplit) int {
+	column := data.Columns[split.node.SplitFeature]
+	mid := 0
+	for i, r := range rows {
+		if split.goesLeft[column[r]] {
+			rows[i], rows[mid] = rows[mid], rows[i]
+			mid++
+		}
+	}
+	return mid
+}
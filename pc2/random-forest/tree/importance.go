@@ -0,0 +1,131 @@
+package tree
+
+import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/preprocess"
+	"adult/random"
+)
+
+// Número de tramos en que se reparten las filas al calcular la precisión fuera de bolsa
+const oobChunks = 64
+
+// Precisión fuera de bolsa: cada registro de entrenamiento se clasifica solo con los árboles que no lo
+// tuvieron en su muestra (inBag[t][i]); los registros que usaron todos los árboles no cuentan
+func OOBAccuracy(trees []*DecisionTree, weights []float64, inBag [][]bool, records []preprocess.Record, encoder *encoding.Encoder, pool *Pool) float64 {
+	var correct, total [oobChunks]int
+	chunkSize := (len(records) + oobChunks - 1) / oobChunks
+	tasks := make([]func(), 0, oobChunks)
+	for c := 0; c < oobChunks; c++ {
+		start, end := c*chunkSize, min((c+1)*chunkSize, len(records))
+		if start >= end {
+			break
+		}
+		tasks = append(tasks, func() {
+			voters := make([]*DecisionTree, 0, len(trees))
+			var voterWeights []float64
+			for i := start; i < end; i++ {
+				voters, voterWeights = voters[:0], voterWeights[:0]
+				for t, tree := range trees {
+					if !inBag[t][i] {
+						voters = append(voters, tree)
+						voterWeights = append(voterWeights, treeWeight(weights, t))
+					}
+				}
+				if len(voters) == 0 {
+					continue
+				}
+				total[c]++
+				if classifier.LabelFromProba(EnsembleProba(voters, voterWeights, FeatureVector(records[i], encoder))) == records[i].Income {
+					correct[c]++
+				}
+			}
+		})
+	}
+	pool.Run(tasks...)
+
+	sumCorrect, sumTotal := 0, 0
+	for c := range correct {
+		sumCorrect += correct[c]
+		sumTotal += total[c]
+	}
+	if sumTotal == 0 {
+		return 0
+	}
+	return float64(sumCorrect) / float64(sumTotal)
+}
+
+// Importancia por impureza: reducción de impureza ponderada por los ejemplos de cada nodo, sumada por
+// característica, normalizada en cada árbol y promediada entre árboles (indexada por preprocess.Feature)
+func ImpurityImportance(trees []*DecisionTree, criterion Criterion) []float64 {
+	importance := make([]float64, len(preprocess.AllFeatures))
+	for _, tree := range trees {
+		decrease := make([]float64, len(importance))
+		tree.addImpurityDecrease(criterion, decrease)
+		sum := 0.0
+		for _, d := range decrease {
+			sum += d
+		}
+		if sum == 0 {
+			continue // Árbol de una sola hoja
+		}
+		for f, d := range decrease {
+			importance[f] += d / sum / float64(len(trees))
+		}
+	}
+	return importance
+}
+
+// Acumular la reducción de impureza de los nodos internos del subárbol
+func (tree *DecisionTree) addImpurityDecrease(criterion Criterion, decrease []float64) {
+	if tree.Prediction != "" {
+		return
+	}
+	weighted := func(node *DecisionTree) float64 {
+		total := node.Counts[0] + node.Counts[1]
+		return float64(total) * impurity(node.Counts, total, criterion)
+	}
+	decrease[tree.SplitFeature] += weighted(tree) - weighted(tree.Left) - weighted(tree.Right)
+	tree.Left.addImpurityDecrease(criterion, decrease)
+	tree.Right.addImpurityDecrease(criterion, decrease)
+}
+
+// Importancia por permutación: caída de la precisión sobre los registros al permutar los valores de
+// cada característica (una tarea por característica en el pool; permutaciones derivadas de la semilla)
+func PermutationImportance(trees []*DecisionTree, weights []float64, records []preprocess.Record, encoder *encoding.Encoder, seed int64, pool *Pool) []float64 {
+	vectors := make([][]float64, len(records))
+	for i, record := range records {
+		vectors[i] = FeatureVector(record, encoder)
+	}
+	baseline := permutedAccuracy(trees, weights, vectors, records, 0, nil)
+
+	importance := make([]float64, len(preprocess.AllFeatures))
+	tasks := make([]func(), len(importance))
+	for _, feature := range preprocess.AllFeatures {
+		tasks[feature] = func() {
+			perm := random.Derive(seed, int(feature)).Perm(len(records))
+			importance[feature] = baseline - permutedAccuracy(trees, weights, vectors, records, feature, perm)
+		}
+	}
+	pool.Run(tasks...)
+	return importance
+}
+
+// Precisión con la columna feature tomada del registro perm[i] (perm nil para no permutar)
+func permutedAccuracy(trees []*DecisionTree, weights []float64, vectors [][]float64, records []preprocess.Record, feature preprocess.Feature, perm []int) float64 {
+	if len(records) == 0 {
+		return 0
+	}
+	features := make([]float64, len(preprocess.AllFeatures))
+	correct := 0
+	for i, record := range records {
+		copy(features, vectors[i])
+		if perm != nil {
+			features[feature] = vectors[perm[i]][feature]
+		}
+		if classifier.LabelFromProba(EnsembleProba(trees, weights, features)) == record.Income {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(records))
+}
@@ -0,0 +1,39 @@
+package tree
+
+import "sync"
+
+// Pool acotado de workers compartido por todos los árboles del bosque y por las tareas
+// internas de cada árbol (evaluación de características y construcción de subárboles)
+type Pool struct {
+	slots chan struct{}
+}
+
+// Crear un pool con el número de workers indicado
+func NewPool(workers int) *Pool {
+	return &Pool{slots: make(chan struct{}, max(1, workers))}
+}
+
+// Ejecutar las tareas y esperar a que terminen. Cada tarea usa un worker libre si lo hay;
+// si el pool está lleno se ejecuta en la goroutine actual, así las tareas anidadas nunca
+// se bloquean esperando un worker. Con un pool nil todas las tareas son secuenciales.
+func (p *Pool) Run(tasks ...func()) {
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		if p == nil {
+			task()
+			continue
+		}
+		select {
+		case p.slots <- struct{}{}:
+			wg.Add(1)
+			go func(task func()) {
+				defer wg.Done()
+				defer func() { <-p.slots }() // Liberar el worker
+				task()
+			}(task)
+		default:
+			task()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,46 @@
+package tree
+
+import "math"
+
+// Podar el árbol por coste-complejidad (eslabón más débil): se colapsa en hoja, una y otra vez, el nodo
+// interno cuya poda aumenta menos el riesgo por hoja eliminada, mientras ese aumento no supere alpha o el
+// árbol tenga más de maxLeaves hojas (0 sin límite). El riesgo de un nodo es su impureza ponderada por la
+// fracción de ejemplos de la raíz que llegan a él
+func (tree *DecisionTree) Prune(alpha float64, maxLeaves int, criterion Criterion) {
+	rootTotal := tree.Counts[0] + tree.Counts[1]
+	if rootTotal == 0 {
+		return // Árbol sin distribución de clases (guardado antes de registrarla)
+	}
+	risk := func(node *DecisionTree) float64 {
+		total := node.Counts[0] + node.Counts[1]
+		return float64(total) / float64(rootTotal) * impurity(node.Counts, total, criterion)
+	}
+
+	for tree.Prediction == "" {
+		_, leaves, link, gain := tree.weakestLink(risk)
+		if !(alpha > 0 && gain <= alpha) && !(maxLeaves > 0 && leaves > maxLeaves) {
+			return
+		}
+		*link = DecisionTree{Prediction: labelFor(link.Counts), Counts: link.Counts}
+	}
+}
+
+// Recorrer el subárbol y devolver su riesgo, su número de hojas y el nodo interno con menor ganancia por
+// hoja g(t) = (R(t) - R(T_t)) / (hojas(T_t) - 1); en caso de empate se prefiere el nodo más cercano a la raíz
+func (tree *DecisionTree) weakestLink(risk func(*DecisionTree) float64) (float64, int, *DecisionTree, float64) {
+	if tree.Prediction != "" {
+		return risk(tree), 1, nil, math.Inf(1)
+	}
+	leftRisk, leftLeaves, leftLink, leftGain := tree.Left.weakestLink(risk)
+	rightRisk, rightLeaves, rightLink, rightGain := tree.Right.weakestLink(risk)
+	subtreeRisk, leaves := leftRisk+rightRisk, leftLeaves+rightLeaves
+
+	link, gain := tree, (risk(tree)-subtreeRisk)/float64(leaves-1)
+	if leftGain < gain {
+		link, gain = leftLink, leftGain
+	}
+	if rightGain < gain {
+		link, gain = rightLink, rightGain
+	}
+	return subtreeRisk, leaves, link, gain
+}
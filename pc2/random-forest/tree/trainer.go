@@ -0,0 +1,61 @@
+package tree
+
+import (
+	"adult/encoding"
+	"adult/preprocess"
+	"math/rand"
+)
+
+// Datos de entrenamiento preparados una sola vez y compartidos (solo lectura) por todos los árboles
+// del bosque: los ejemplos codificados en el modo exacto o las columnas cuantizadas en el modo histograma
+type Trainer struct {
+	config   Config
+	examples []Example
+	data     *Binned
+}
+
+// Preparar los registros según el modo de construcción de la configuración
+func NewTrainer(records []preprocess.Record, encoder *encoding.Encoder, config Config) (*Trainer, error) {
+	t := &Trainer{config: config}
+	if !config.Histogram {
+		t.examples = EncodeRecords(records, encoder)
+		return t, nil
+	}
+	data, err := Quantize(records, encoder, config.MaxBins)
+	if err != nil {
+		return nil, err
+	}
+	t.data = data
+	return t, nil
+}
+
+// Número de filas de entrenamiento
+func (t *Trainer) NumRows() int {
+	if t.data != nil {
+		return t.data.NumRows()
+	}
+	return len(t.examples)
+}
+
+// Construir un árbol con una muestra bootstrap sorteada con rng, o con todas las filas si la configuración
+// no usa bootstrap (pool nil para hacerlo secuencialmente); devuelve también qué filas entraron en la
+// muestra (las demás son fuera de bolsa para ese árbol)
+func (t *Trainer) BuildTree(rng *rand.Rand, pool *Pool) (*DecisionTree, []bool) {
+	weights := make([]int32, t.NumRows())
+	if t.config.Bootstrap() {
+		weights = BootstrapWeights(len(weights), rng)
+	} else {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	inBag := make([]bool, len(weights))
+	for i, w := range weights {
+		inBag[i] = w > 0
+	}
+	if t.data != nil {
+		// La muestra son pesos por fila: no se copia ningún registro
+		return BuildHistogramConcurrent(t.data, weights, t.config, rng, pool), inBag
+	}
+	return BuildConcurrent(weightedSample(t.examples, weights), t.config, rng, pool), inBag
+}
@@ -0,0 +1,509 @@
+package tree
+
+import (
+	"adult/encoding"
+	"adult/preprocess"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// Número de clases (0: <=50K, 1: >50K)
+const numClasses = 2
+
+// Etiqueta de cada clase
+var classLabels = [numClasses]string{preprocess.NegativeLabel, preprocess.PositiveLabel}
+
+// Criterio de impureza usado para evaluar las divisiones
+type Criterion int
+
+const (
+	Gini    Criterion = iota // Impureza de Gini
+	Entropy                  // Entropía (ganancia de información)
+)
+
+// Parámetros de construcción de un árbol
+type Config struct {
+	MaxDepth    int       // Profundidad máxima
+	Criterion   Criterion // Criterio de impureza
+	MaxFeatures int       // Características candidatas por nodo (mtry); 0 usa la raíz cuadrada del total
+	Histogram   bool      // Entrenar con las columnas cuantizadas (Quantize) en lugar de los valores exactos
+	MaxBins     int       // Intervalos por columna numérica en el modo histograma; 0 usa el máximo (256)
+	ExtraTrees  bool      // Árboles extremadamente aleatorios: un corte al azar por característica candidata
+	Sampling    Sampling  // Muestra de filas de cada árbol del bosque
+
+	// Criterios de parada (los valores cero no imponen ninguna restricción)
+	MinSamplesSplit     int     // Ejemplos mínimos para intentar dividir un nodo
+	MinSamplesLeaf      int     // Ejemplos mínimos en cada hijo de una división
+	MinImpurityDecrease float64 // Reducción mínima de impureza (en boosting, de la ganancia), ponderada por la fracción de ejemplos del nodo
+
+	// Poda posterior por coste-complejidad (eslabón más débil)
+	MaxLeafNodes int     // Número máximo de hojas tras la poda
+	CCPAlpha     float64 // Se podan los subárboles cuya ganancia por hoja no supera este valor
+}
+
+// Muestra de filas con la que se construye cada árbol del bosque
+type Sampling int
+
+const (
+	AutoSampling      Sampling = iota // Bootstrap salvo en el modo ExtraTrees, que usa todas las filas
+	BootstrapSampling                 // Muestra bootstrap (con reemplazo)
+	FullSampling                      // Todas las filas una vez
+)
+
+// Indica si cada árbol se construye con una muestra bootstrap
+func (config Config) Bootstrap() bool {
+	if config.Sampling == AutoSampling {
+		return !config.ExtraTrees
+	}
+	return config.Sampling == BootstrapSampling
+}
+
+// Estructura para representar un árbol de decisión (nodo)
+type DecisionTree struct {
+	SplitFeature preprocess.Feature // Columna del registro usada para dividir
+	Threshold    float64            // Columnas numéricas: los valores menores van a la izquierda
+	Categories   []int              // Columnas categóricas: códigos de las categorías que van a la izquierda
+	Left         *DecisionTree
+	Right        *DecisionTree
+	Prediction   string
+	Counts       [numClasses]int // Ejemplos de cada clase que llegaron al nodo durante el entrenamiento
+	Value        float64         // Árboles de gradient boosting: contribución de la hoja al logit
+	splitBin     int             // Modo histograma: último intervalo de la rama izquierda (solo durante el entrenamiento)
+}
+
+// Registro ya codificado: vector indexado por preprocess.Feature y clase (1 para >50K)
+type Example struct {
+	Features []float64
+	Label    int
+}
+
+// Vector de características de un registro indexado por preprocess.Feature: el valor de las
+// columnas numéricas y el código de la categoría (según el codificador) de las categóricas
+func FeatureVector(record preprocess.Record, encoder *encoding.Encoder) []float64 {
+	features := make([]float64, len(preprocess.AllFeatures))
+	for _, feature := range preprocess.AllFeatures {
+		if feature.IsCategorical() {
+			features[feature] = float64(encoder.Code(feature, record.Category(feature)))
+		} else {
+			features[feature] = record.Numeric(feature)
+		}
+	}
+	return features
+}
+
+// Codificar los registros una sola vez antes de construir los árboles
+func EncodeRecords(records []preprocess.Record, encoder *encoding.Encoder) []Example {
+	examples := make([]Example, len(records))
+	for i, record := range records {
+		label := 0
+		if record.Income == preprocess.PositiveLabel {
+			label = 1
+		}
+		examples[i] = Example{Features: FeatureVector(record, encoder), Label: label}
+	}
+	return examples
+}
+
+// Bootstrap sample: pesos con cuántas veces se elige cada fila al sortear n filas con reemplazo
+func BootstrapWeights(n int, rng *rand.Rand) []int32 {
+	weights := make([]int32, n)
+	for i := 0; i < n; i++ {
+		weights[rng.Intn(n)]++
+	}
+	return weights
+}
+
+// Materializar la muestra: cada ejemplo aparece tantas veces como indica su peso
+func weightedSample(examples []Example, weights []int32) []Example {
+	sample := make([]Example, 0, len(examples))
+	for i, w := range weights {
+		for ; w > 0; w-- {
+			sample = append(sample, examples[i])
+		}
+	}
+	return sample
+}
+
+// Número mínimo de ejemplos de un nodo para repartir su trabajo entre los workers del pool
+const parallelCutoff = 20000
+
+// Construir un árbol de decisión CART con los ejemplos
+func Build(examples []Example, config Config, rng *rand.Rand) *DecisionTree {
+	return BuildConcurrent(examples, config, rng, nil)
+}
+
+// Construir un árbol repartiendo en el pool la evaluación de las características candidatas y
+// los subárboles de los nodos grandes; con la misma semilla produce exactamente el mismo árbol que Build
+func BuildConcurrent(examples []Example, config Config, rng *rand.Rand, pool *Pool) *DecisionTree {
+	b := &builder{config: config, pool: pool, rootSamples: len(examples)}
+	return b.finish(b.buildTree(examples, config.MaxDepth, rng))
+}
+
+// Estado compartido durante la construcción de un árbol
+type builder struct {
+	config      Config
+	pool        *Pool // nil para construir secuencialmente
+	rootSamples int   // Ejemplos de la raíz, para ponderar MinImpurityDecrease
+}
+
+// Indica si un nodo a la profundidad restante depth con esos ejemplos debe ser hoja sin buscar división
+func (b *builder) stop(depth int, counts [numClasses]int) bool {
+	total := counts[0] + counts[1]
+	return depth == 0 || total < max(2, b.config.MinSamplesSplit) || total < 2*b.minLeaf()
+}
+
+// Ejemplos mínimos en cada hijo
+func (b *builder) minLeaf() int {
+	return max(1, b.config.MinSamplesLeaf)
+}
+
+// Indica si la reducción de impureza de la mejor división alcanza MinImpurityDecrease
+func (b *builder) enoughDecrease(counts [numClasses]int, parentImpurity, childImpurity float64) bool {
+	fraction := float64(counts[0]+counts[1]) / float64(b.rootSamples)
+	return fraction*(parentImpurity-childImpurity) >= b.config.MinImpurityDecrease
+}
+
+// Aplicar la poda posterior configurada al árbol ya construido
+func (b *builder) finish(root *DecisionTree) *DecisionTree {
+	if b.config.CCPAlpha > 0 || b.config.MaxLeafNodes > 0 {
+		root.Prune(b.config.CCPAlpha, b.config.MaxLeafNodes, b.config.Criterion)
+	}
+	return root
+}
+
+// Indica si el trabajo de un nodo con n ejemplos se reparte entre los workers
+func (b *builder) parallel(n int) bool {
+	return b.pool != nil && n >= parallelCutoff
+}
+
+// Función recursiva para construir un árbol de decisión
+func (b *builder) buildTree(examples []Example, depth int, rng *rand.Rand) *DecisionTree {
+	counts := classCounts(examples)
+	if b.stop(depth, counts) {
+		return &DecisionTree{Prediction: labelFor(counts), Counts: counts}
+	}
+
+	node, ok := b.chooseBestSplit(examples, counts, rng)
+	if !ok {
+		// Nodo puro o sin ninguna división válida que reduzca la impureza lo suficiente
+		return &DecisionTree{Prediction: labelFor(counts), Counts: counts}
+	}
+	leftExamples, rightExamples := node.splitExamples(examples)
+	if len(leftExamples) == 0 || len(rightExamples) == 0 {
+		// Una partición vacía dejaría un hijo sin ejemplos: el nodo se queda como hoja
+		return &DecisionTree{Prediction: labelFor(counts), Counts: counts}
+	}
+	node.Counts = counts
+
+	// Cada hijo recibe su propio generador, derivado antes de construirlos, para que el resultado
+	// no dependa de si los subárboles se construyen en paralelo o uno detrás de otro
+	leftRng := rand.New(rand.NewSource(rng.Int63()))
+	rightRng := rand.New(rand.NewSource(rng.Int63()))
+	buildLeft := func() { node.Left = b.buildTree(leftExamples, depth-1, leftRng) }
+	buildRight := func() { node.Right = b.buildTree(rightExamples, depth-1, rightRng) }
+	if b.parallel(len(examples)) {
+		b.pool.Run(buildLeft, buildRight)
+	} else {
+		buildLeft()
+		buildRight()
+	}
+	return node
+}
+
+// Función para hacer predicciones con un árbol de decisión
+func (tree *DecisionTree) Predict(features []float64) string {
+	if tree.Prediction != "" {
+		return tree.Prediction
+	}
+
+	// Usar el valor de la característica para determinar el camino en el árbol
+	if tree.goesLeft(features) {
+		return tree.Left.Predict(features)
+	}
+	return tree.Right.Predict(features)
+}
+
+// Probabilidad de la clase positiva según la distribución de clases de la hoja a la que llega el vector
+func (tree *DecisionTree) PredictProba(features []float64) float64 {
+	if tree.Prediction != "" {
+		return tree.leafProba()
+	}
+	if tree.goesLeft(features) {
+		return tree.Left.PredictProba(features)
+	}
+	return tree.Right.PredictProba(features)
+}
+
+// Fracción de ejemplos positivos de la hoja
+func (tree *DecisionTree) leafProba() float64 {
+	total := tree.Counts[0] + tree.Counts[1]
+	if total == 0 {
+		// Hoja sin distribución de clases (modelo guardado antes de registrarla): vale lo que su etiqueta
+		if tree.Prediction == preprocess.PositiveLabel {
+			return 1
+		}
+		return 0
+	}
+	return float64(tree.Counts[1]) / float64(total)
+}
+
+// Indica si un vector de características va a la rama izquierda del nodo
+// (la misma regla se usa al particionar los ejemplos y al predecir)
+func (tree *DecisionTree) goesLeft(features []float64) bool {
+	if tree.SplitFeature.IsCategorical() {
+		return slices.Contains(tree.Categories, int(features[tree.SplitFeature]))
+	}
+	return features[tree.SplitFeature] < tree.Threshold
+}
+
+// Elegir la mejor división (mayor reducción de impureza) entre un subconjunto aleatorio de características;
+// devuelve un nodo interno sin hijos
+func (b *builder) chooseBestSplit(examples []Example, parentCounts [numClasses]int, rng *rand.Rand) (*DecisionTree, bool) {
+	criterion, minLeaf := b.config.Criterion, b.minLeaf()
+	parentImpurity := impurity(parentCounts, len(examples), criterion)
+	if parentImpurity == 0 {
+		return nil, false
+	}
+
+	// Evaluar cada característica candidata (en paralelo si el nodo es grande)
+	candidates := candidateFeatures(len(preprocess.AllFeatures), b.config.MaxFeatures, rng)
+	rngs := b.candidateRngs(len(candidates), rng)
+	results := make([]splitResult, len(candidates))
+	tasks := make([]func(), len(candidates))
+	for i, feature := range candidates {
+		tasks[i] = func() {
+			if b.config.ExtraTrees {
+				results[i].node, results[i].impurity, results[i].ok = randomSplit(examples, feature, parentCounts, criterion, minLeaf, rngs[i])
+			} else if feature.IsCategorical() {
+				results[i].node, results[i].impurity, results[i].ok = bestCategorySplit(examples, feature, parentCounts, criterion, minLeaf)
+			} else {
+				results[i].node, results[i].impurity, results[i].ok = bestThresholdSplit(examples, feature, parentCounts, criterion, minLeaf)
+			}
+		}
+	}
+	if b.parallel(len(examples)) {
+		b.pool.Run(tasks...)
+	} else {
+		for _, task := range tasks {
+			task()
+		}
+	}
+
+	// Elegir el mejor resultado en el orden de las candidatas (igual que la versión secuencial)
+	var best *DecisionTree
+	bestImpurity := parentImpurity
+	for _, result := range results {
+		if result.ok && result.impurity < bestImpurity {
+			best, bestImpurity = result.node, result.impurity
+		}
+	}
+	if best == nil || !b.enoughDecrease(parentCounts, parentImpurity, bestImpurity) {
+		return nil, false
+	}
+
+	return best, true
+}
+
+// Generadores propios de cada característica candidata en el modo ExtraTrees, derivados en orden antes
+// de evaluarlas para que el resultado no dependa de si se evalúan en paralelo (nil en el modo clásico)
+func (b *builder) candidateRngs(n int, rng *rand.Rand) []*rand.Rand {
+	if !b.config.ExtraTrees {
+		return nil
+	}
+	rngs := make([]*rand.Rand, n)
+	for i := range rngs {
+		rngs[i] = rand.New(rand.NewSource(rng.Int63()))
+	}
+	return rngs
+}
+
+// Mejor división encontrada para una característica
+type splitResult struct {
+	node     *DecisionTree
+	impurity float64
+	ok       bool
+}
+
+// Elegir mtry características distintas al azar (Fisher-Yates parcial)
+func candidateFeatures(numFeatures, maxFeatures int, rng *rand.Rand) []preprocess.Feature {
+	if maxFeatures <= 0 {
+		maxFeatures = int(math.Sqrt(float64(numFeatures)))
+	}
+	maxFeatures = max(1, min(maxFeatures, numFeatures))
+
+	features := make([]preprocess.Feature, numFeatures)
+	for i := range features {
+		features[i] = preprocess.Feature(i)
+	}
+	for i := 0; i < maxFeatures; i++ {
+		j := i + rng.Intn(numFeatures-i)
+		features[i], features[j] = features[j], features[i]
+	}
+	return features[:maxFeatures]
+}
+
+// Recorrer los valores ordenados de una columna numérica y devolver la división por umbral con menor impureza ponderada
+func bestThresholdSplit(examples []Example, feature preprocess.Feature, parentCounts [numClasses]int, criterion Criterion, minLeaf int) (*DecisionTree, float64, bool) {
+	type point struct {
+		value float64
+		label int
+	}
+	points := make([]point, len(examples))
+	for i, ex := range examples {
+		points[i] = point{value: ex.Features[feature], label: ex.Label}
+	}
+	sort.Slice(points, func(a, b int) bool { return points[a].value < points[b].value })
+
+	total := len(points)
+	var leftCounts [numClasses]int
+	bestThreshold, bestImpurity := 0.0, math.Inf(1)
+	found := false
+	for i := 0; i < total-1; i++ {
+		leftCounts[points[i].label]++
+		if points[i].value == points[i+1].value {
+			continue // Solo se puede dividir entre valores distintos
+		}
+		if i+1 < minLeaf || total-i-1 < minLeaf {
+			continue // Algún hijo quedaría con menos ejemplos que MinSamplesLeaf
+		}
+
+		var rightCounts [numClasses]int
+		for c := range rightCounts {
+			rightCounts[c] = parentCounts[c] - leftCounts[c]
+		}
+		weighted := weightedImpurity(leftCounts, rightCounts, criterion)
+		if weighted < bestImpurity {
+			bestImpurity = weighted
+			bestThreshold = (points[i].value + points[i+1].value) / 2 // Punto medio entre valores consecutivos
+			found = true
+		}
+	}
+
+	return &DecisionTree{SplitFeature: feature, Threshold: bestThreshold}, bestImpurity, found
+}
+
+// Buscar la mejor partición de las categorías de una columna categórica. Con dos clases basta
+// ordenar las categorías por su proporción de positivos y probar los prefijos (Breiman et al.)
+func bestCategorySplit(examples []Example, feature preprocess.Feature, parentCounts [numClasses]int, criterion Criterion, minLeaf int) (*DecisionTree, float64, bool) {
+	var counts [][numClasses]int
+	for _, ex := range examples {
+		code := int(ex.Features[feature])
+		for code >= len(counts) {
+			counts = append(counts, [numClasses]int{})
+		}
+		counts[code][ex.Label]++
+	}
+
+	var present []int
+	for code, c := range counts {
+		if c[0]+c[1] > 0 {
+			present = append(present, code)
+		}
+	}
+	if len(present) < 2 {
+		return nil, 0, false
+	}
+	positiveRate := func(code int) float64 {
+		return float64(counts[code][1]) / float64(counts[code][0]+counts[code][1])
+	}
+	sort.SliceStable(present, func(a, b int) bool { return positiveRate(present[a]) < positiveRate(present[b]) })
+
+	var leftCounts [numClasses]int
+	bestPrefix, bestImpurity := 0, math.Inf(1)
+	for i := 0; i < len(present)-1; i++ {
+		for c := range leftCounts {
+			leftCounts[c] += counts[present[i]][c]
+		}
+		var rightCounts [numClasses]int
+		for c := range rightCounts {
+			rightCounts[c] = parentCounts[c] - leftCounts[c]
+		}
+		if leftCounts[0]+leftCounts[1] < minLeaf || rightCounts[0]+rightCounts[1] < minLeaf {
+			continue
+		}
+		weighted := weightedImpurity(leftCounts, rightCounts, criterion)
+		if weighted < bestImpurity {
+			bestPrefix, bestImpurity = i+1, weighted
+		}
+	}
+	if bestPrefix == 0 {
+		return nil, 0, false
+	}
+
+	left := slices.Clone(present[:bestPrefix])
+	slices.Sort(left)
+	return &DecisionTree{SplitFeature: feature, Categories: left}, bestImpurity, true
+}
+
+// Impureza ponderada por el tamaño de los dos hijos de una división
+func weightedImpurity(leftCounts, rightCounts [numClasses]int, criterion Criterion) float64 {
+	leftTotal, rightTotal := 0, 0
+	for c := 0; c < numClasses; c++ {
+		leftTotal += leftCounts[c]
+		rightTotal += rightCounts[c]
+	}
+	total := float64(leftTotal + rightTotal)
+	return (float64(leftTotal)*impurity(leftCounts, leftTotal, criterion) +
+		float64(rightTotal)*impurity(rightCounts, rightTotal, criterion)) / total
+}
+
+// Impureza de un nodo según el criterio elegido
+func impurity(counts [numClasses]int, total int, criterion Criterion) float64 {
+	if total == 0 {
+		return 0
+	}
+	result := 0.0
+	if criterion == Entropy {
+		for _, count := range counts {
+			if count > 0 {
+				p := float64(count) / float64(total)
+				result -= p * math.Log2(p)
+			}
+		}
+		return result
+	}
+
+	result = 1.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		result -= p * p
+	}
+	return result
+}
+
+// Contar los ejemplos de cada clase
+func classCounts(examples []Example) [numClasses]int {
+	var counts [numClasses]int
+	for _, ex := range examples {
+		counts[ex.Label]++
+	}
+	return counts
+}
+
+// Dividir los ejemplos en dos subconjuntos según la regla del nodo
+func (tree *DecisionTree) splitExamples(examples []Example) ([]Example, []Example) {
+	var left, right []Example
+
+	for _, ex := range examples {
+		if tree.goesLeft(ex.Features) {
+			left = append(left, ex)
+		} else {
+			right = append(right, ex)
+		}
+	}
+
+	return left, right
+}
+
+// Etiqueta de la clase con más ejemplos (los empates van a la clase negativa)
+func labelFor(counts [numClasses]int) string {
+	best := 0
+	for c := 1; c < numClasses; c++ {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+	return classLabels[best]
+}
@@ -0,0 +1,63 @@
+package concurrent
+
+import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/preprocess"
+	"adult/scaling"
+	"fmt"
+	"testing"
+)
+
+// El entrenamiento con mutex y el Hogwild! deben llegar a una precisión parecida con cualquier número de
+// workers (0 se trata como 1)
+func TestHogwildAccuracy(t *testing.T) {
+	records, err := preprocess.ReadRecords("../adult.data", 10000)
+	if err != nil {
+		t.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	train, test := records[:8000], records[8000:]
+	encoder := encoding.Fit(train, encoding.OneHot)
+	scaler := scaling.Fit(train, encoder.Transform, scaling.ZScore, 1)
+
+	for _, hogwild := range []bool{false, true} {
+		for _, workers := range []int{0, 1, 4} {
+			t.Run(fmt.Sprintf("hogwild=%v/workers=%d", hogwild, workers), func(t *testing.T) {
+				svm := NewSVM(encoder, scaler, 5, 0.01, 0.001, workers)
+				svm.Hogwild = hogwild
+				if err := svm.Fit(train); err != nil {
+					t.Fatalf("Fit: %v", err)
+				}
+				if accuracy := classifier.Accuracy(svm, test); accuracy < 0.8 {
+					t.Errorf("precisión %.4f, se esperaba al menos 0.8", accuracy)
+				}
+			})
+		}
+	}
+}
+
+// Entrenamiento con mutex frente a Hogwild! con los mismos datos, hiperparámetros y workers
+func BenchmarkHogwild(b *testing.B) {
+	records, err := preprocess.ReadRecords("../adult.data", 20000)
+	if err != nil {
+		b.Fatalf("no se pudieron cargar los datos: %v", err)
+	}
+	encoder := encoding.Fit(records, encoding.OneHot)
+	scaler := scaling.Fit(records, encoder.Transform, scaling.ZScore, 1)
+
+	for _, hogwild := range []bool{false, true} {
+		name := "mutex"
+		if hogwild {
+			name = "hogwild"
+		}
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s/workers=%d", name, workers), func(b *testing.B) {
+				for range b.N {
+					svm := NewSVM(encoder, scaler, 2, 0.01, 0.001, workers)
+					svm.Hogwild = hogwild
+					svm.Fit(records)
+				}
+			})
+		}
+	}
+}
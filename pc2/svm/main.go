@@ -1,28 +1,73 @@
 package main
 
 import (
+	"adult/metrics"
+	"adult/persist"
+	"adult/preprocess"
+	"flag"
 	"fmt"
 	"svm/concurrent"
-	"svm/preprocess"
 	"svm/sequential"
 )
 
+// Semilla para que las ejecuciones sean reproducibles
+const seed = 42
+
 func main() {
+	saveDir := flag.String("save", "", "directorio donde guardar los modelos entrenados (vacío para no guardarlos)")
+	epochs := flag.Int("epocas", 100, "número máximo de épocas de entrenamiento")
+	patience := flag.Int("paciencia", 5, "épocas sin mejorar la pérdida de validación antes de parar (0 para no parar antes)")
+	validation := flag.Float64("validacion", 0.1, "fracción de los registros de entrenamiento reservada para la validación y la parada temprana (0 para no reservar)")
+	metricsPath := flag.String("metricas", "", "archivo CSV donde escribir las métricas de cada época (vacío para no escribirlas)")
+	flag.Parse()
+
 	// Cargar y preprocesar los datos
 	fmt.Println("Cargando y preprocesando datos...")
-	records, err := preprocess.LoadAndPreprocess("adult.data", 1000000) // Cargar 1 millón de registros
+	records, err := preprocess.LoadAndPreprocess("adult.data", 1000000, seed) // Cargar 1 millón de registros
 	if err != nil {
 		fmt.Printf("Error al cargar los datos: %v\n", err)
 		return
 	}
 
-	fmt.Println("Datos cargados correctamente.")
+	// Cargar el conjunto de prueba oficial para que la precisión sea comparable con los resultados publicados
+	testRecords, err := preprocess.LoadTestData("adult.test")
+	if err != nil {
+		fmt.Printf("Error al cargar los datos de prueba: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Datos cargados correctamente (%d de entrenamiento, %d de prueba).\n", len(records), len(testRecords))
+
+	var epochMetrics *metrics.CSVWriter
+	if *metricsPath != "" {
+		epochMetrics, err = metrics.Create(*metricsPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer func() {
+			if err := epochMetrics.Close(); err != nil {
+				fmt.Printf("Error al escribir las métricas: %v\n", err)
+				return
+			}
+			fmt.Printf("Métricas por época guardadas en %s\n", *metricsPath)
+		}()
+	}
+
+	seqConfig := sequential.Config{Epochs: *epochs, Patience: *patience, ValidationFraction: *validation, Observer: epochMetrics.Run("svm_secuencial")}
+	concConfig := concurrent.Config{Epochs: *epochs, Patience: *patience, ValidationFraction: *validation, Observer: epochMetrics.Run("svm_concurrente")}
 
 	// **Versión secuencial de SVM**
 	fmt.Println("\n--- SVM Secuencial ---")
-	sequential.TestSequentialSVM(records)
+	seqModel := sequential.TestSequentialSVM(records, testRecords, seed, seqConfig)
+	if seqModel != nil && *saveDir != "" {
+		persist.SaveAll(seqModel.Save, *saveDir, "svm_secuencial")
+	}
 
 	// **Versión concurrente de SVM**
 	fmt.Println("\n--- SVM Concurrente ---")
-	concurrent.TestConcurrentSVM(records)
+	concModel := concurrent.TestConcurrentSVM(records, testRecords, seed, concConfig)
+	if concModel != nil && *saveDir != "" {
+		persist.SaveAll(concModel.Save, *saveDir, "svm_concurrente")
+	}
 }
@@ -1,35 +1,88 @@
 package sequential
 
 import (
+	"adult/classifier"
+	"adult/encoding"
+	"adult/metrics"
+	"adult/persist"
+	"adult/preprocess"
+	"adult/scaling"
 	"fmt"
-	"svm/preprocess"
+	"math"
+	"slices"
 	"time"
 )
 
+// El SVM implementa la interfaz común de clasificadores
+var _ classifier.Classifier = (*SVM)(nil)
+
+// Épocas sin mejorar la pérdida de validación antes de parar, por defecto
+const defaultPatience = 5
+
 // Estructura para representar un modelo SVM
 type SVM struct {
-	Weights []float64
-	Bias    float64
-	Lambda  float64 // Parámetro de regularización
-	LR      float64 // Tasa de aprendizaje
+	Weights            []float64
+	Bias               float64
+	Lambda             float64           // Parámetro de regularización
+	LR                 float64           // Tasa de aprendizaje
+	Epochs             int               // Número de épocas que usa Fit
+	ValidationFraction float64           // Fracción de registros que Fit reserva para la validación y la parada temprana (0 para no reservar)
+	Patience           int               // Épocas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	BestEpoch          int               // Época cuyos pesos se conservaron (la de menor pérdida de validación; 0 sin validación)
+	Seed               int64             // Semilla de la separación de validación
+	Encoder            *encoding.Encoder // Codificador de características ajustado con los datos de entrenamiento
+	Scaler             *scaling.Scaler   // Escalador ajustado con los datos de entrenamiento (nil para no escalar)
+
+	observers []metrics.Observer // Reciben las métricas de cada época (no se guardan con el modelo)
+}
+
+// Función para crear un modelo SVM sin entrenar
+func NewSVM(encoder *encoding.Encoder, scaler *scaling.Scaler, epochs int, lambda float64, lr float64) *SVM {
+	return &SVM{
+		Weights:  make([]float64, encoder.NumFeatures()), // Un peso por cada característica codificada
+		Bias:     0,
+		Lambda:   lambda,
+		LR:       lr,
+		Encoder:  encoder,
+		Scaler:   scaler,
+		Epochs:   epochs,
+		Patience: defaultPatience,
+	}
 }
 
 // Función para entrenar el modelo SVM secuencial
-func TrainSVM(records []preprocess.Record, epochs int, lambda float64, lr float64) *SVM {
-	svm := &SVM{
-		Weights: make([]float64, 6), // Asumimos 6 características numéricas (edad, fnlwgt, etc.)
-		Bias:    0,
-		Lambda:  lambda,
-		LR:      lr,
+func TrainSVM(records []preprocess.Record, encoder *encoding.Encoder, scaler *scaling.Scaler, epochs int, lambda float64, lr float64) *SVM {
+	svm := NewSVM(encoder, scaler, epochs, lambda, lr)
+	svm.Fit(records)
+	return svm
+}
+
+// Añadir un observador que recibe las métricas de cada época del entrenamiento
+func (svm *SVM) AddObserver(observer metrics.Observer) {
+	svm.observers = append(svm.observers, observer)
+}
+
+// Función para entrenar el SVM con los registros, reservando svm.ValidationFraction de ellos para las
+// métricas de validación (implementa classifier.Classifier)
+func (svm *SVM) Fit(records []preprocess.Record) error {
+	if len(records) == 0 {
+		return classifier.ErrNoRecords
 	}
+	records, validation := preprocess.SplitValidation(records, svm.ValidationFraction, svm.Seed)
+	lambda, lr := svm.Lambda, svm.LR
+	start := time.Now()
+	stopping := metrics.NewEarlyStopping(svm.Patience)
 
-	for epoch := 0; epoch < epochs; epoch++ {
+	for epoch := 0; epoch < svm.Epochs; epoch++ {
+		loss := 0.0 // Pérdida de bisagra de cada registro antes de su actualización
 		for _, record := range records {
-			features := extractFeatures(record)
+			features := svm.extractFeatures(record)
 			label := convertLabel(record.Income)
+			margin := label * (dotProduct(svm.Weights, features) + svm.Bias)
+			loss += math.Max(0, 1-margin)
 
 			// Verificar si el ejemplo actual está mal clasificado
-			if label*(dotProduct(svm.Weights, features)+svm.Bias) < 1 {
+			if margin < 1 {
 				// Actualizar los pesos y el sesgo (bias)
 				for i := range svm.Weights {
 					svm.Weights[i] = (1-lr*lambda)*svm.Weights[i] + lr*label*features[i]
@@ -42,36 +95,72 @@ func TrainSVM(records []preprocess.Record, epochs int, lambda float64, lr float6
 				}
 			}
 		}
+		m := metrics.Report(svm.observers, epoch+1, loss/float64(len(records)), start, validation, svm.Evaluate)
+		if len(validation) > 0 && stopping.Check(m, svm.snapshot) {
+			break
+		}
 	}
-	return svm
+	svm.BestEpoch = stopping.Restore()
+	return nil
+}
+
+// Función para copiar los pesos y el sesgo y devolver la función que los restaura (parada temprana)
+func (svm *SVM) snapshot() func() {
+	weights, bias := slices.Clone(svm.Weights), svm.Bias
+	return func() { svm.Weights, svm.Bias = weights, bias }
+}
+
+// Función para calcular la pérdida de bisagra media y la precisión del SVM sobre los registros
+func (svm *SVM) Evaluate(records []preprocess.Record) (loss, accuracy float64) {
+	correct := 0
+	for _, record := range records {
+		score := dotProduct(svm.Weights, svm.extractFeatures(record)) + svm.Bias
+		loss += math.Max(0, 1-convertLabel(record.Income)*score)
+		if labelFromScore(score) == record.Income {
+			correct++
+		}
+	}
+	return loss / float64(len(records)), float64(correct) / float64(len(records))
 }
 
 // Función para predecir con SVM
 func (svm *SVM) Predict(record preprocess.Record) string {
-	features := extractFeatures(record)
-	score := dotProduct(svm.Weights, features) + svm.Bias
+	features := svm.extractFeatures(record)
+	return labelFromScore(dotProduct(svm.Weights, features) + svm.Bias)
+}
 
+// Etiqueta según el lado del hiperplano en que cae la puntuación
+func labelFromScore(score float64) string {
 	if score >= 0 {
-		return ">50K"
+		return preprocess.PositiveLabel
 	}
-	return "<=50K"
-}
-
-// Función para extraer características numéricas de un registro
-func extractFeatures(record preprocess.Record) []float64 {
-	return []float64{
-		float64(record.Age),
-		float64(record.Fnlwgt),
-		float64(record.EducationNum),
-		float64(record.CapitalGain),
-		float64(record.CapitalLoss),
-		float64(record.HoursPerWeek),
+	return preprocess.NegativeLabel
+}
+
+// Función para estimar la probabilidad de la clase positiva (función logística de la distancia al hiperplano)
+func (svm *SVM) PredictProba(record preprocess.Record) float64 {
+	features := svm.extractFeatures(record)
+	score := dotProduct(svm.Weights, features) + svm.Bias
+	return 1.0 / (1.0 + math.Exp(-score))
+}
+
+// Función para predecir las etiquetas de varios registros
+func (svm *SVM) PredictBatch(records []preprocess.Record) []string {
+	return classifier.BatchPredict(svm.Predict, records, 1)
+}
+
+// Función para extraer las características (numéricas y categóricas codificadas) de un registro
+func (svm *SVM) extractFeatures(record preprocess.Record) []float64 {
+	features := svm.Encoder.Transform(record)
+	if svm.Scaler != nil {
+		features = svm.Scaler.Transform(features)
 	}
+	return features
 }
 
 // Función para convertir la etiqueta de ingreso a -1 o 1
 func convertLabel(income string) float64 {
-	if income == ">50K" {
+	if income == preprocess.PositiveLabel {
 		return 1.0
 	}
 	return -1.0
@@ -86,30 +175,60 @@ func dotProduct(vec1, vec2 []float64) float64 {
 	return result
 }
 
-// Función para probar el SVM secuencial
-func TestSequentialSVM(records []preprocess.Record) {
-	// Dividir datos en entrenamiento y prueba (80% entrenamiento, 20% prueba)
-	numTrain := int(0.8 * float64(len(records)))
-	trainData := records[:numTrain]
-	testData := records[numTrain:]
+// Tipo de modelo en los archivos guardados (el mismo que en la versión concurrente, son intercambiables)
+const modelKind = "svm"
+
+// Versión del formato de la SVM en disco
+const modelVersion = 1
+
+// Guardar el SVM (pesos, codificador y escalador) en disco: JSON si la ruta termina en .json, formato binario en otro caso
+func (svm *SVM) Save(path string) error {
+	return persist.Save(path, modelKind, modelVersion, svm)
+}
+
+// Cargar un modelo guardado con Save
+func Load(path string) (*SVM, error) {
+	svm := &SVM{}
+	if err := persist.Load(path, modelKind, modelVersion, svm); err != nil {
+		return nil, err
+	}
+	return svm, nil
+}
+
+// Configuración del entrenamiento de TestSequentialSVM
+type Config struct {
+	Epochs             int              // Número máximo de épocas
+	Patience           int              // Épocas sin mejorar la pérdida de validación antes de parar (<= 0 para no parar antes)
+	ValidationFraction float64          // Fracción de los registros reservada para la validación (0 para no reservar)
+	Observer           metrics.Observer // Recibe las métricas de cada época (nil para no informar)
+}
 
+// Función para probar el SVM secuencial (devuelve el modelo entrenado)
+func TestSequentialSVM(trainData, testData []preprocess.Record, seed int64, config Config) *SVM {
 	// Entrenar SVM
 	fmt.Println("Entrenando SVM Secuencial...")
+	encoder := encoding.Fit(trainData, encoding.OneHot)
+	scaler := scaling.Fit(trainData, encoder.Transform, scaling.ZScore, 1)
+	svm := NewSVM(encoder, scaler, config.Epochs, 0.01, 0.001) // lambda = 0.01, tasa de aprendizaje = 0.001
+	svm.Patience, svm.ValidationFraction, svm.Seed = config.Patience, config.ValidationFraction, seed
+	if config.Observer != nil {
+		svm.AddObserver(config.Observer)
+	}
 	start := time.Now()
-	svm := TrainSVM(trainData, 100, 0.01, 0.001) // 100 épocas, lambda = 0.01, tasa de aprendizaje = 0.001
+	if err := svm.Fit(trainData); err != nil {
+		fmt.Printf("Error al entrenar: %v\n", err)
+		return nil
+	}
 	elapsed := time.Since(start)
 	fmt.Printf("Tiempo de entrenamiento: %s\n", elapsed)
+	if svm.BestEpoch > 0 {
+		fmt.Printf("Parada temprana: pesos de la época %d (de %d como máximo)\n", svm.BestEpoch, config.Epochs)
+	}
 
 	// Probar el modelo
 	fmt.Println("Probando SVM Secuencial...")
-	correct := 0
-	for _, record := range testData {
-		prediction := svm.Predict(record)
-		if prediction == record.Income {
-			correct++
-		}
-	}
-
-	accuracy := float64(correct) / float64(len(testData))
+	accuracy := classifier.Accuracy(svm, testData)
 	fmt.Printf("Precisión: %.2f%%\n", accuracy*100)
+
+	return svm
 }